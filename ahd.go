@@ -0,0 +1,219 @@
+package arw
+
+import (
+	"image"
+	"math"
+)
+
+//AHD implements Adaptive Homogeneity-Directed interpolation as described by Hirakawa and Parks.
+//Green is interpolated both horizontally and vertically, and per pixel the direction which results
+//in the most homogeneous neighbourhood in CIELab space is chosen.
+type AHD struct{}
+
+const (
+	ahdTile   = 256
+	ahdMargin = 3
+	ahdBorder = ahdMargin + 2
+)
+
+//cbrtTable maps [0, 2) to the CIELab f(t) function.
+var cbrtTable = buildCbrtTable()
+
+func buildCbrtTable() []float32 {
+	table := make([]float32, 0x10000)
+	for i := range table {
+		t := float64(i) / 0x8000
+		if t > 0.008856 {
+			table[i] = float32(math.Cbrt(t))
+		} else {
+			table[i] = float32(7.787*t + 16.0/116.0)
+		}
+	}
+	return table
+}
+
+func labF(t float64) float32 {
+	i := int(t * 0x8000)
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(cbrtTable):
+		i = len(cbrtTable) - 1
+	}
+	return cbrtTable[i]
+}
+
+//lab converts a 14 bit rgb triplet in to CIELab, treating it as linear sRGB.
+func lab(rgb [3]int32) [3]float32 {
+	r, g, b := float64(rgb[0])/0x3fff, float64(rgb[1])/0x3fff, float64(rgb[2])/0x3fff
+	x := labF((0.412453*r + 0.357580*g + 0.180423*b) / 0.950456)
+	y := labF(0.212671*r + 0.715160*g + 0.072169*b)
+	z := labF((0.019334*r + 0.119193*g + 0.950227*b) / 1.088754)
+	return [3]float32{116*y - 16, 500 * (x - y), 200 * (y - z)}
+}
+
+func (a AHD) Demosaic(cfa *CFA) *RGB14 {
+	img := NewRGB14(cfa.Rect)
+	a.demosaicRows(cfa, img, cfa.Rect.Min.Y, cfa.Rect.Max.Y)
+	return img
+}
+
+func (AHD) demosaicRows(cfa *CFA, img *RGB14, y0, y1 int) {
+	borderInterpolate(cfa, img, ahdBorder, y0, y1)
+
+	r := cfa.Rect
+	inner := r.Inset(ahdBorder).Intersect(image.Rect(r.Min.X, y0, r.Max.X, y1))
+	for top := inner.Min.Y; top < inner.Max.Y; top += ahdTile {
+		for left := inner.Min.X; left < inner.Max.X; left += ahdTile {
+			tile := image.Rect(left, top, left+ahdTile, top+ahdTile)
+			ahdTileAt(cfa, img, tile.Intersect(inner))
+		}
+	}
+}
+
+//ahdTileAt interpolates a single tile, working buffers include a margin around the tile.
+func ahdTileAt(cfa *CFA, img *RGB14, tile image.Rectangle) {
+	work := tile.Inset(-ahdMargin)
+	w, h := work.Dx(), work.Dy()
+	idx := func(x, y int) int { return (y-work.Min.Y)*w + (x - work.Min.X) }
+
+	var green [2][]int32
+	var rgb [2][][3]int32
+	var labs [2][][3]float32
+	var homo [2][]uint8
+	for d := 0; d < 2; d++ {
+		green[d] = make([]int32, w*h)
+		rgb[d] = make([][3]int32, w*h)
+		labs[d] = make([][3]float32, w*h)
+		homo[d] = make([]uint8, w*h)
+	}
+
+	//Interpolate green horizontally (d = 0) and vertically (d = 1)
+	for y := work.Min.Y; y < work.Max.Y; y++ {
+		for x := work.Min.X; x < work.Max.X; x++ {
+			i := idx(x, y)
+			own := int32(cfa.at(x, y))
+			if cfa.Color(x, y) == cfaGreen {
+				green[0][i], green[1][i] = own, own
+				continue
+			}
+			for d := 0; d < 2; d++ {
+				dx, dy := 1-d, d
+				g1, g2 := int32(cfa.at(x-dx, y-dy)), int32(cfa.at(x+dx, y+dy))
+				c1, c2 := int32(cfa.at(x-2*dx, y-2*dy)), int32(cfa.at(x+2*dx, y+2*dy))
+				g := (g1+g2)/2 + (2*own-c1-c2)/4
+				lo, hi := g1, g2
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				if g < lo {
+					g = lo
+				}
+				if g > hi {
+					g = hi
+				}
+				green[d][i] = g
+			}
+		}
+	}
+
+	//Interpolate red and blue from the colour differences to green, then convert to CIELab
+	inner := work.Inset(1)
+	for y := inner.Min.Y; y < inner.Max.Y; y++ {
+		for x := inner.Min.X; x < inner.Max.X; x++ {
+			i := idx(x, y)
+			own := cfa.Color(x, y)
+			for d := 0; d < 2; d++ {
+				var sum, count [3]int32
+				for ny := y - 1; ny <= y+1; ny++ {
+					for nx := x - 1; nx <= x+1; nx++ {
+						c := cfa.Color(nx, ny)
+						if c == own || c == cfaGreen {
+							continue
+						}
+						sum[c] += int32(cfa.at(nx, ny)) - green[d][idx(nx, ny)]
+						count[c]++
+					}
+				}
+
+				var px [3]int32
+				px[cfaGreen] = green[d][i]
+				for c := range px {
+					if c != cfaGreen && count[c] > 0 {
+						px[c] = int32(clamp14(green[d][i] + sum[c]/count[c]))
+					}
+				}
+				px[own] = int32(cfa.at(x, y))
+				rgb[d][i] = px
+				labs[d][i] = lab(px)
+			}
+		}
+	}
+
+	//Build homogeneity maps, counting the neighbours which are within the same tolerance in both directions
+	neighbours := [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	inner = work.Inset(2)
+	for y := inner.Min.Y; y < inner.Max.Y; y++ {
+		for x := inner.Min.X; x < inner.Max.X; x++ {
+			i := idx(x, y)
+			var ldiff, abdiff [2][4]float32
+			for d := 0; d < 2; d++ {
+				for n, o := range neighbours {
+					a, b := labs[d][i], labs[d][idx(x+o[0], y+o[1])]
+					ldiff[d][n] = float32(math.Abs(float64(a[0] - b[0])))
+					abdiff[d][n] = (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2])
+				}
+			}
+			leps := min32(max32(ldiff[0][0], ldiff[0][1]), max32(ldiff[1][2], ldiff[1][3]))
+			abeps := min32(max32(abdiff[0][0], abdiff[0][1]), max32(abdiff[1][2], abdiff[1][3]))
+			for d := 0; d < 2; d++ {
+				for n := range neighbours {
+					if ldiff[d][n] <= leps && abdiff[d][n] <= abeps {
+						homo[d][i]++
+					}
+				}
+			}
+		}
+	}
+
+	//Pick the most homogeneous direction for every pixel of the tile
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			var hm [2]int
+			for ny := y - 1; ny <= y+1; ny++ {
+				for nx := x - 1; nx <= x+1; nx++ {
+					hm[0] += int(homo[0][idx(nx, ny)])
+					hm[1] += int(homo[1][idx(nx, ny)])
+				}
+			}
+
+			i := idx(x, y)
+			var px [3]int32
+			switch {
+			case hm[0] > hm[1]:
+				px = rgb[0][i]
+			case hm[0] < hm[1]:
+				px = rgb[1][i]
+			default:
+				for c := range px {
+					px[c] = (rgb[0][i][c] + rgb[1][i][c]) / 2
+				}
+			}
+			img.set(x-cfa.Rect.Min.X, y-cfa.Rect.Min.Y, pixel16{R: uint16(px[0]), G: uint16(px[1]), B: uint16(px[2])})
+		}
+	}
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
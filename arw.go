@@ -2,14 +2,12 @@
 package arw
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"reflect"
 	"strings"
-	"unsafe"
 )
 
 //CIPA DC-008-2012 Table 1
@@ -31,26 +29,7 @@ func (e EXIFIFD) String() string {
 	var result []string
 	result = append(result, fmt.Sprintf("Count: %v", e.Count))
 	for i := range e.FIA {
-		var val string
-		val = e.FIAvals[i].String()
-		if int(e.FIA[i].Count)*e.FIA[i].Type.Len() <= 4 {
-			switch e.FIA[i].Type {
-			case BYTE:
-				val = fmt.Sprintf("%x %x %x %x", (e.FIA[i].Offset>>24)&0xff, (e.FIA[i].Offset>>16)&0xff, (e.FIA[i].Offset>>8)&0xff, (e.FIA[i].Offset>>0)&0xff)
-			case ASCII:
-				val = fmt.Sprintf("%c %c %c %c", (e.FIA[i].Offset>>24)&0xff, (e.FIA[i].Offset>>16)&0xff, (e.FIA[i].Offset>>8)&0xff, (e.FIA[i].Offset>>0)&0xff)
-			case SHORT:
-				val = fmt.Sprintf("%v %v", (e.FIA[i].Offset>>16)&0xffff, (e.FIA[i].Offset>>0)&0xffff)
-			case LONG:
-				val = fmt.Sprint(e.FIA[i].Offset)
-			case UNDEFINED:
-				val = fmt.Sprintf("%x %x %x %x", (e.FIA[i].Offset>>24)&0xff, (e.FIA[i].Offset>>16)&0xff, (e.FIA[i].Offset>>8)&0xff, (e.FIA[i].Offset>>0)&0xff)
-			case SSHORT:
-				val = fmt.Sprintf("%v %v", int16((e.FIA[i].Offset>>16)&0xffff), int16((e.FIA[i].Offset>>0)&0xffff))
-			case SLONG:
-				val = fmt.Sprint(int32(e.FIA[i].Offset))
-			}
-		}
+		val := e.FIAvals[i].String()
 		result = append(result, fmt.Sprintf("%v: %v", e.FIA[i].Tag, val))
 	}
 	result = append(result, fmt.Sprintf("Offset to next EXIFIFD: %v", e.Offset))
@@ -75,23 +54,26 @@ type FIAval struct {
 	long   *[]uint32
 	slong  *[]int32
 	rat    *[]float32
+	frac   *[]Rational //RATIONAL and SRATIONAL values as stored, rat holds their quotients
 }
 
+//ShotInfoTags is the fixed part of the ShotInfo makernote tag, the face information follows at FaceInfoOffset.
 type ShotInfoTags struct {
-	_               [2]byte
+	ByteOrder       [2]byte
 	FaceInfoOffset  uint16
+	_               [2]byte
 	SonyDateTime    [20]byte
 	SonyImageHeight uint16
 	SonyImageWidth  uint16
+	_               [18]byte
 	FacesDetected   uint16
 	FaceInfoLength  uint16
 	MetaVersion     [16]byte
-	_               [4]byte
-	FaceInfo1
-	FaceInfo2
 }
 
+//FaceInfo1 is the face information at offset 0x48 with 32 bytes per face, positions are top, left, height and width.
 type FaceInfo1 struct {
+	FacesDetected uint16
 	Face1Position [4]uint16
 	_             [24]byte
 	Face2Position [4]uint16
@@ -110,7 +92,9 @@ type FaceInfo1 struct {
 	_             [24]byte
 }
 
+//FaceInfo2 is the face information at offset 0x5e with 37 bytes per face, positions are top, left, height and width.
 type FaceInfo2 struct {
+	FacesDetected uint16
 	Face1Position [4]uint16
 	_             [29]byte
 	Face2Position [4]uint16
@@ -202,8 +186,13 @@ const (
 	ResolutionUnit            IFDtag = 296
 	Software                  IFDtag = 305
 	DateTime                  IFDtag = 306
+	Artist                    IFDtag = 315
 	Whitepoint                IFDtag = 318
 	PrimaryChromaticities     IFDtag = 319
+	TileWidth                 IFDtag = 322
+	TileLength                IFDtag = 323
+	TileOffsets               IFDtag = 324
+	TileByteCounts            IFDtag = 325
 	SubIFDs                   IFDtag = 330
 
 	JPEGInterchangeFormat       IFDtag = 513
@@ -213,7 +202,10 @@ const (
 
 	XMP IFDtag = 700 //http://www.adobe.com/products/xmp.html Some completely useless XML format
 
+	Copyright IFDtag = 33432
+
 	ShotInfo         IFDtag = 0x3000
+	PreviewImage     IFDtag = 0x2001
 	FileFormat       IFDtag = 0xb000
 	SonyModelID      IFDtag = 0xb001
 	CreativeStyle    IFDtag = 0xb020
@@ -221,6 +213,9 @@ const (
 	FullImageSize    IFDtag = 0xb02b
 	PreviewImageSize IFDtag = 0xb02c
 	Tag9400          IFDtag = 0x9400 //Tag9400A-C
+	Tag9050          IFDtag = 0x9050 //Tag9050A-C
+	Tag9406          IFDtag = 0x9406
+	Tag2010          IFDtag = 0x2010 //Tag2010B-I
 
 	//Following tags have been scavenged from the internet, most likely to do with the Sony raw data in ARW
 	SonyRawFileType IFDtag = 0x7000
@@ -271,6 +266,7 @@ const (
 	DistortionCorrParams          IFDtag = 0x7982
 
 	ExifTag             IFDtag = 34665
+	InterColorProfile   IFDtag = 34675
 	GPSTag              IFDtag = 34853
 	InteroperabilityTag IFDtag = 40965
 	PrintImageMatching  IFDtag = 50341
@@ -346,6 +342,66 @@ const (
 	CFAPattern2              IFDtag = 0x828e
 )
 
+//Tags of the GPS IFD, CIPA DC-008-2012 Chapter 4.6.6
+//These share their numbers with tags of other IFDs so the names are prefixed.
+const (
+	GPSVersionID         IFDtag = 0x00
+	GPSLatitudeRef       IFDtag = 0x01
+	GPSLatitude          IFDtag = 0x02
+	GPSLongitudeRef      IFDtag = 0x03
+	GPSLongitude         IFDtag = 0x04
+	GPSAltitudeRef       IFDtag = 0x05
+	GPSAltitude          IFDtag = 0x06
+	GPSTimeStamp         IFDtag = 0x07
+	GPSSatellites        IFDtag = 0x08
+	GPSStatus            IFDtag = 0x09
+	GPSMeasureMode       IFDtag = 0x0a
+	GPSDOP               IFDtag = 0x0b
+	GPSSpeedRef          IFDtag = 0x0c
+	GPSSpeed             IFDtag = 0x0d
+	GPSTrackRef          IFDtag = 0x0e
+	GPSTrack             IFDtag = 0x0f
+	GPSImgDirectionRef   IFDtag = 0x10
+	GPSImgDirection      IFDtag = 0x11
+	GPSMapDatum          IFDtag = 0x12
+	GPSDestLatitudeRef   IFDtag = 0x13
+	GPSDestLatitude      IFDtag = 0x14
+	GPSDestLongitudeRef  IFDtag = 0x15
+	GPSDestLongitude     IFDtag = 0x16
+	GPSDestBearingRef    IFDtag = 0x17
+	GPSDestBearing       IFDtag = 0x18
+	GPSDestDistanceRef   IFDtag = 0x19
+	GPSDestDistance      IFDtag = 0x1a
+	GPSProcessingMethod  IFDtag = 0x1b
+	GPSAreaInformation   IFDtag = 0x1c
+	GPSDateStamp         IFDtag = 0x1d
+	GPSDifferential      IFDtag = 0x1e
+	GPSHPositioningError IFDtag = 0x1f
+)
+
+//Tags of the Adobe Digital Negative Specification 1.4, the DNG black and white level are prefixed to keep them apart from the Sony tags.
+const (
+	DNGVersion             IFDtag = 50706
+	DNGBackwardVersion     IFDtag = 50707
+	UniqueCameraModel      IFDtag = 50708
+	LocalizedCameraModel   IFDtag = 50709
+	CFAPlaneColor          IFDtag = 50710
+	CFALayout              IFDtag = 50711
+	LinearizationTable     IFDtag = 50712
+	BlackLevelRepeatDim    IFDtag = 50713
+	DNGBlackLevel          IFDtag = 50714
+	DNGWhiteLevel          IFDtag = 50717
+	DefaultScale           IFDtag = 50718
+	ColorMatrix1           IFDtag = 50721
+	ColorMatrix2           IFDtag = 50722
+	AnalogBalance          IFDtag = 50727
+	AsShotNeutral          IFDtag = 50728
+	BaselineExposure       IFDtag = 50730
+	CalibrationIlluminant1 IFDtag = 50778
+	CalibrationIlluminant2 IFDtag = 50779
+	OriginalRawFileName    IFDtag = 50827
+)
+
 //go:generate stringer -type=sonyRawFile
 type sonyRawFile uint16
 
@@ -390,139 +446,309 @@ func (i IFDtype) Len() int {
 	}
 }
 
-//Anyone who thinks I'm switching byte order mid program is sorely mistaken.
-var b binary.ByteOrder = binary.LittleEndian
+//File is a TIFF document together with the byte order announced in its header.
+//Every IFD read through a File uses that order, so several files may be parsed concurrently.
+type File struct {
+	r      io.ReadSeeker
+	order  binary.ByteOrder
+	Header TIFFHeader
+}
+
+//NewFile parses the TIFF header of r and returns a File reading in the byte order it declares.
+func NewFile(r io.ReadSeeker) (*File, error) {
+	header, order, err := parseHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &File{r: r, order: order, Header: header}, nil
+}
+
+//ByteOrder returns the byte order of the file.
+func (f *File) ByteOrder() binary.ByteOrder {
+	return f.order
+}
+
+//ExtractMetaData will return the IFD at offset using the byte order of the file.
+func (f *File) ExtractMetaData(offset int64, whence int) (EXIFIFD, error) {
+	return extractMetaData(f.r, f.order, offset, whence)
+}
+
+//IFDs returns IFD0 and every IFD chained to it through the next IFD offsets.
+func (f *File) IFDs() ([]EXIFIFD, error) {
+	var ifds []EXIFIFD
+	seen := make(map[uint32]bool)
+	for offset := f.Header.Offset; offset != 0; {
+		if seen[offset] {
+			return ifds, errors.New("IFD chain loops back to offset: " + fmt.Sprint(offset))
+		}
+		if len(ifds) == maxIFDChain {
+			return ifds, errors.New("IFD chain longer than " + fmt.Sprint(maxIFDChain))
+		}
+		seen[offset] = true
+
+		ifd, err := f.ExtractMetaData(int64(offset), 0)
+		if err != nil {
+			return ifds, err
+		}
+		ifds = append(ifds, ifd)
+		offset = ifd.Offset
+	}
+	return ifds, nil
+}
 
 //Parses a TIFF header to determine first IFD and endianness.
 func ParseHeader(r io.ReadSeeker) (TIFFHeader, error) {
+	header, _, err := parseHeader(r)
+	return header, err
+}
+
+func parseHeader(r io.ReadSeeker) (TIFFHeader, binary.ByteOrder, error) {
+	var order binary.ByteOrder
 	endian := make([]byte, 2)
-	r.Read(endian)
+	if _, err := io.ReadFull(r, endian); err != nil {
+		return TIFFHeader{}, nil, err
+	}
 	switch string(endian) {
 	case "II":
-		b = binary.LittleEndian
+		order = binary.LittleEndian
 	case "MM":
-		b = binary.BigEndian
+		order = binary.BigEndian
 	default:
-		return TIFFHeader{}, errors.New("failed to determine endianness: " + fmt.Sprint(endian))
+		return TIFFHeader{}, nil, errors.New("failed to determine endianness: " + fmt.Sprint(endian))
+	}
+	if _, err := r.Seek(-2, 1); err != nil {
+		return TIFFHeader{}, nil, err
 	}
-	r.Seek(-2, 1)
 
 	var header TIFFHeader
-	binary.Read(r, b, &header)
+	if err := binary.Read(r, order, &header); err != nil {
+		return header, order, err
+	}
 	if header.FortyTwo != 42 {
-		return header, errors.New("found an endianness marker but no fixed 42, offset might be unreliable")
+		return header, order, errors.New("found an endianness marker but no fixed 42, offset might be unreliable")
 	}
-	return header, nil
+	return header, order, nil
 }
 
-//ExtractMetadata will return the first IFD from a TIFF document.
+//Limits which keep crafted files from exhausting memory or time.
+const (
+	maxIFDEntries = 4096     //Entries in a single IFD
+	maxValueSize  = 64 << 20 //Bytes of values in a single entry
+	maxIFDChain   = 256      //IFDs in a chain of next IFD offsets
+)
+
+//ExtractMetadata will return the IFD at offset of the TIFF document read from r, in the byte order of its header.
+//Documents without a valid header are read as little endian.
+//
+//Deprecated: Use NewFile and File.ExtractMetaData, which read the header once.
 func ExtractMetaData(r io.ReadSeeker, offset int64, whence int) (meta EXIFIFD, err error) {
-	r.Seek(offset, whence)
-	binary.Read(r, b, &meta.Count)
+	var order binary.ByteOrder = binary.LittleEndian
+	if _, err := r.Seek(0, 0); err != nil {
+		return meta, err
+	}
+	if _, o, err := parseHeader(r); err == nil {
+		order = o
+	}
+	return extractMetaData(r, order, offset, whence)
+}
+
+func extractMetaData(r io.ReadSeeker, order binary.ByteOrder, offset int64, whence int) (meta EXIFIFD, err error) {
+	size, err := r.Seek(0, 2)
+	if err != nil {
+		return meta, err
+	}
+	start, err := r.Seek(offset, whence)
+	if err != nil {
+		return meta, err
+	}
+	if start < 0 || start+2 > size {
+		return meta, errors.New("IFD offset outside of file: " + fmt.Sprint(start))
+	}
+
+	if err := binary.Read(r, order, &meta.Count); err != nil {
+		return meta, err
+	}
+	if meta.Count > maxIFDEntries {
+		return meta, errors.New("too many IFD entries: " + fmt.Sprint(meta.Count))
+	}
+	if start+2+int64(meta.Count)*12+4 > size {
+		return meta, errors.New("IFD runs past the end of the file: " + fmt.Sprint(start))
+	}
 	meta.FIA = make([]IFDFIA, int(meta.Count))
-	binary.Read(r, b, &meta.FIA)
-	binary.Read(r, b, &meta.Offset)
+	if err := binary.Read(r, order, &meta.FIA); err != nil {
+		return meta, err
+	}
+	if err := binary.Read(r, order, &meta.Offset); err != nil {
+		return meta, err
+	}
 
 	meta.FIAvals = make([]FIAval, len(meta.FIA))
 	for n, interop := range meta.FIA {
 		meta.FIAvals[n].IFDtype = interop.Type
+		if interop.Type.Len() < 0 {
+			//There is no way to decode values of unknown types
+			continue
+		}
 
-		//Offset field is actually the value
-		if uint32(interop.Type.Len())*interop.Count <= 4 {
-			switch interop.Type {
-			case UNDEFINED, ASCII, BYTE:
-				values := make([]byte, interop.Count)
-				for i := range values {
-					values[i] = byte(((interop.Offset << uint32(8*i)) & 0xff000000) >> 24)
-				}
-				meta.FIAvals[n].ascii = &values
-			case SHORT:
-				values := make([]uint16, interop.Count)
-				for i := range values {
-					values[i] = uint16(((interop.Offset << uint32(16*i)) & 0xffff0000) >> 16)
-				}
-				meta.FIAvals[n].short = &values
-			case SSHORT:
-				values := make([]int16, interop.Count)
-				for i := range values {
-					values[i] = int16(((interop.Offset << uint32(16*i)) & 0xffff0000) >> 16)
-				}
-				meta.FIAvals[n].sshort = &values
-			case LONG:
-				values := []uint32{interop.Count}
-				meta.FIAvals[n].long = &values
-			case SLONG:
-				values := []int32{int32(interop.Count)}
-				meta.FIAvals[n].slong = &values
-			}
+		valueSize := int64(interop.Type.Len()) * int64(interop.Count)
+		if valueSize > maxValueSize {
+			return meta, errors.New("values of " + fmt.Sprint(interop.Tag) + " are too large: " + fmt.Sprint(valueSize))
+		}
+
+		//Offset field is actually the value, put the bytes back in file order and read them like any other value
+		var vr io.Reader
+		if valueSize <= 4 {
+			inline := make([]byte, 4)
+			order.PutUint32(inline, interop.Offset)
+			vr = bytes.NewReader(inline)
 		} else {
-			r.Seek(int64(interop.Offset), 0)
-			switch interop.Type {
-			case UNDEFINED, ASCII, BYTE:
-				values := make([]byte, interop.Count)
-				binary.Read(r, b, &values)
-				meta.FIAvals[n].ascii = &values
-			case SHORT:
-				values := make([]uint16, interop.Count)
-				binary.Read(r, b, &values)
-				meta.FIAvals[n].short = &values
-			case SSHORT:
-				values := make([]int16, interop.Count)
-				binary.Read(r, b, &values)
-				meta.FIAvals[n].sshort = &values
-			case LONG:
-				values := make([]uint32, interop.Count)
-				binary.Read(r, b, &values)
-				meta.FIAvals[n].long = &values
-			case SLONG:
-				values := make([]int32, interop.Count)
-				binary.Read(r, b, &values)
-				meta.FIAvals[n].slong = &values
-			case RATIONAL:
-				values := make([]uint32, interop.Count*2)
-				binary.Read(r, b, &values)
-				floats := make([]float32, interop.Count)
-				for i := range floats {
-					floats[i] = float32(values[i*2]) / float32(values[(i*2)+1])
-				}
-				meta.FIAvals[n].rat = &floats
-			case SRATIONAL:
-				values := make([]int32, interop.Count*2)
-				binary.Read(r, b, &values)
-				floats := make([]float32, interop.Count)
-				for i := range floats {
-					floats[i] = float32(values[i*2]) / float32(values[(i*2)+1])
-				}
-				meta.FIAvals[n].rat = &floats
+			if int64(interop.Offset)+valueSize > size {
+				return meta, errors.New("values of " + fmt.Sprint(interop.Tag) + " outside of file: " + fmt.Sprint(interop.Offset))
 			}
+			if _, err := r.Seek(int64(interop.Offset), 0); err != nil {
+				return meta, err
+			}
+			vr = r
+		}
+
+		if err := readValues(vr, order, interop, &meta.FIAvals[n]); err != nil {
+			return meta, err
 		}
 	}
 
-	return
+	return meta, nil
 }
 
-//TODO(sjon): We probably want to generate this pad ourselves if we ever discover versions of ARW which use a real key.
-//All current variants just have a default placeholder in the key field.
-//TODO(sjon): BUG this function can't be called multiple times since the pad is consumed. for now the pad is put in the function body.
+//readValues decodes the values of a single IFD entry.
+func readValues(r io.Reader, order binary.ByteOrder, interop IFDFIA, val *FIAval) error {
+	switch interop.Type {
+	case UNDEFINED, ASCII, BYTE:
+		values := make([]byte, interop.Count)
+		val.ascii = &values
+		return binary.Read(r, order, &values)
+	case SHORT:
+		values := make([]uint16, interop.Count)
+		val.short = &values
+		return binary.Read(r, order, &values)
+	case SSHORT:
+		values := make([]int16, interop.Count)
+		val.sshort = &values
+		return binary.Read(r, order, &values)
+	case LONG:
+		values := make([]uint32, interop.Count)
+		val.long = &values
+		return binary.Read(r, order, &values)
+	case SLONG:
+		values := make([]int32, interop.Count)
+		val.slong = &values
+		return binary.Read(r, order, &values)
+	case RATIONAL:
+		values := make([]uint32, interop.Count*2)
+		if err := binary.Read(r, order, &values); err != nil {
+			return err
+		}
+		floats := make([]float32, interop.Count)
+		fracs := make([]Rational, interop.Count)
+		for i := range floats {
+			floats[i] = float32(values[i*2]) / float32(values[(i*2)+1])
+			fracs[i] = Rational{int64(values[i*2]), int64(values[(i*2)+1])}
+		}
+		val.rat = &floats
+		val.frac = &fracs
+	case SRATIONAL:
+		values := make([]int32, interop.Count*2)
+		if err := binary.Read(r, order, &values); err != nil {
+			return err
+		}
+		floats := make([]float32, interop.Count)
+		fracs := make([]Rational, interop.Count)
+		for i := range floats {
+			floats[i] = float32(values[i*2]) / float32(values[(i*2)+1])
+			fracs[i] = Rational{int64(values[i*2]), int64(values[(i*2)+1])}
+		}
+		val.rat = &floats
+		val.frac = &fracs
+	}
+	return nil
+}
+
+//sr2PlaceholderKey is the SR2SubIFDKey stored by all current variants.
+const sr2PlaceholderKey = 0x44332211
 
+//DecryptSR2 reads and decrypts the SR2SubIFD of length bytes at offset with the placeholder key, nil is returned when it can not be read.
+//
+//Deprecated: Use DecryptSR2Key with the key from the SR2SubIFDKey tag, which also reports read errors.
 func DecryptSR2(r io.ReadSeeker, offset uint32, length uint32) []byte {
-	var pad = []uint32{0xae567acf, 0x3758b80d, 0x7c2906a5, 0x1a30e50c, 0xa4fff8d4, 0x5ad0ba02, 0xb0adfde3, 0x80c0bf1c, 0x28a40a6e, 0xb5210a3c, 0x3013ee1b, 0x6ac26b41, 0x306ec9eb, 0xbfc7c3fa, 0x01fa4ee0, 0xaa0b5077, 0x63280f17, 0x2b98271b, 0xc4a483ee, 0x0327efd8, 0x4f1919f3, 0x507e9187, 0x167b353b, 0xa7b2fcbe, 0xb2c45890, 0xef99db72, 0x497fdb56, 0x91564e98, 0xf777078d, 0xfd9e2bd5, 0x7c11b8b7, 0xd890cb9a, 0x16cd7e75, 0x4b1cc09f, 0xd4b88d85, 0x2719170a, 0x85ebe6e1, 0xd80aae2b, 0xa2a6d6c8, 0xfe277243, 0x4e9a6052, 0x4d5ab8d1, 0xd9796c35, 0x66fb9425, 0x2fc719ce, 0x574259e8, 0xed7debf6, 0x62729b9b, 0x8475e571, 0x6b6084e7, 0xd2101c0e, 0x12243ef8, 0xaccaf2ff, 0xf388743f, 0xfdb4dde3, 0xc259958e, 0xa3fc5e38, 0x63a2c363, 0xbd9006b7, 0x43f7adda, 0x3dd8b01e, 0x41aadc72, 0x01916c53, 0x04bae250, 0x7892b89b, 0x8b207c44, 0xf206a891, 0x1e353d29, 0x14292114, 0x2b2b82da, 0xcd5f120b, 0x6a3c7ee7, 0xb2ed663e, 0x822ef87b, 0xff64e96a, 0xd0250c39, 0x9a121fa9, 0xa516e885, 0xcbecec87, 0xea66c879, 0xa3fce75d, 0x9fe040f8, 0xd12016b4, 0xeb0c1103, 0xe5b8e3d3, 0xe8d8a3f6, 0x6930ebcf, 0x06a865eb, 0x18111138, 0xdde18c3b, 0xe342f4ef, 0xb793d2a1, 0xf7a7caaf, 0xd4e4bc34, 0x29ca7d80, 0xc6eedc2a, 0xbcdb6e5f, 0x2514c03c, 0x2a2326be, 0xc7f5392c, 0x2cf191c2, 0xc4c3f321, 0x0ca46ff9, 0x066c941b, 0x40aafc77, 0x855fcf74, 0x981c261d, 0x0667b6de, 0xb16db5d5, 0x0771f254, 0x53e22691, 0x022c8814, 0xc41f2789, 0x0abaf480, 0x2ffb0330, 0x112cf928, 0xd7c94972, 0x362c1b50, 0xf0659484, 0x4f00c4f1, 0x4f58bbed, 0xf258be43, 0x7f7b5ed2, 0x7ab1f464, 0x6046ca7f, 0x11d3954e, 0x3e7a285b, 0x00000000}
+	buf, err := DecryptSR2Key(r, offset, length, sr2PlaceholderKey)
+	if err != nil {
+		return nil
+	}
+	return buf
+}
+
+//DecryptSR2Key reads and decrypts the SR2SubIFD of length bytes at offset with the key from the SR2SubIFDKey tag.
+//The pad is applied to big endian words, whatever the byte order of the file, trailing bytes of a length which is not a multiple of 4 are not encrypted.
+func DecryptSR2Key(r io.ReadSeeker, offset uint32, length uint32, key uint32) ([]byte, error) {
+	size, err := r.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxValueSize || int64(offset)+int64(length) > size {
+		return nil, errors.New("SR2 data outside of file: " + fmt.Sprint(offset, "+", length))
+	}
+
 	buf := make([]byte, length)
-	r.Seek(int64(offset), 0)
-	r.Read(buf)
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&buf))
-	header.Len /= 4
-	header.Cap /= 4
-	data := *(*[]uint32)(unsafe.Pointer(&header))
+	if _, err := r.Seek(int64(offset), 0); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	decryptSR2(buf, key)
+	return buf, nil
+}
 
+//decryptSR2 decrypts buf in place, the pad is a lagged Fibonacci generator seeded from key.
+func decryptSR2(buf []byte, key uint32) {
+	pad := sr2Pad(key)
 	p := 128
-	for i := range data {
+	for i := 0; i+4 <= len(buf); i += 4 {
 		pad[(p-1)&127] = pad[p&127] ^ pad[(p+64)&127]
-		data[i] ^= pad[(p-1)&127]
+		binary.BigEndian.PutUint32(buf[i:], binary.BigEndian.Uint32(buf[i:])^pad[(p-1)&127])
 		p++
 	}
+}
 
-	return buf
+//sr2Pad returns the initial pad for key, the last word is left zero.
+func sr2Pad(key uint32) [128]uint32 {
+	var pad [128]uint32
+	for p := 0; p < 4; p++ {
+		key = key*48828125 + 1
+		pad[p] = key
+	}
+	pad[3] = pad[3]<<1 | (pad[0]^pad[2])>>31
+	for p := 4; p < 127; p++ {
+		pad[p] = (pad[p-4]^pad[p-2])<<1 | (pad[p-3]^pad[p-1])>>31
+	}
+	return pad
+}
+
+//sr2Reader exposes a decrypted SR2 block at its original position in the file, the SR2SubIFD points to values using file offsets.
+type sr2Reader struct {
+	buf    []byte
+	offset int64
+}
+
+func (s sr2Reader) ReadAt(p []byte, off int64) (int, error) {
+	off -= s.offset
+	if off < 0 || off >= int64(len(s.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
 }
 
 //ExtractThumbnail extracts an embedded JPEG thumbnail.
@@ -552,20 +778,40 @@ func (p crawPixelBlock) String() string {
 	return fmt.Sprintf("Max: %v\tMin: %v\nMaxIdx: %v\tMinIdx: %v\nDeltas: %v\n", p.max, p.min, p.maxidx, p.minidx, p.pix)
 }
 
-func (p crawPixelBlock) Decompress() [pixelBlockSize]pixel {
-	var pix [pixelBlockSize]pixel
-	factor := (p.max - p.min) / 128
-	var ordinary int
+//Errors describing why a compressed block could not be decoded.
+var (
+	ErrBlockRange = errors.New("block maximum is below its minimum")
+	ErrBlockIndex = errors.New("block minimum and maximum share a position")
+)
 
+//BlockError reports a damaged CRAW block pair, Err is ErrBlockRange, ErrBlockIndex or io.ErrUnexpectedEOF for truncated data.
+type BlockError struct {
+	StripOffset int64 //Offset of the raw strip in the file
+	Offset      int64 //Offset of the block pair within the strip
+	X, Y        int   //Position of the first pixel of the block pair in the frame
+	Err         error
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("corrupt CRAW block at strip offset %v+%v (x %v, y %v): %v", e.StripOffset, e.Offset, e.X, e.Y, e.Err)
+}
+
+//Unwrap returns Err, so errors.Is can match the cause of a decode failure.
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
+
+func (p crawPixelBlock) Decompress() ([pixelBlockSize]pixel, error) {
+	var pix [pixelBlockSize]pixel
 	if p.max < p.min {
-		log.Println(p)
-		panic("Expected max to be larger than min")
+		return pix, ErrBlockRange
 	}
 	if p.maxidx == p.minidx {
-		log.Println(p)
-		panic("Expected non overlapping min and max position")
+		return pix, ErrBlockIndex
 	}
 
+	factor := (p.max - p.min) / 128
+	var ordinary int
 	for i := 0; i < pixelBlockSize; i++ {
 		switch i {
 		case int(p.maxidx):
@@ -577,14 +823,14 @@ func (p crawPixelBlock) Decompress() [pixelBlockSize]pixel {
 			ordinary++
 		}
 	}
-	return pix
+	return pix, nil
 }
 
 //readCrawBlock reads a 16 byte compressed CRAW block in to a workable datastructure.
-func readCrawBlock(s []byte) crawPixelBlock {
+func readCrawBlock(s []byte, order binary.ByteOrder) crawPixelBlock {
 	var p crawPixelBlock
 
-	val := b.Uint32(s)
+	val := order.Uint32(s)
 	max := uint16(0x7ff & (val >> 0))
 	min := uint16(0x7ff & (val >> 11))
 	maxidx := uint8(0x0f & (val >> 22))
@@ -593,7 +839,7 @@ func readCrawBlock(s []byte) crawPixelBlock {
 	for bit, i := 30, 0; i < len(p.pix); i++ {
 		var val uint16
 		if bit>>3 != 15 { // We will read off the end of the slice if we read a uint16 at the last byte
-			val = b.Uint16(s[bit>>3:])
+			val = order.Uint16(s[bit>>3:])
 		} else {
 			val = uint16(s[15])
 		}
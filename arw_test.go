@@ -1,8 +1,12 @@
 package arw
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"image"
 	"image/png"
+	"io"
 	"os"
 	"testing"
 	"time"
@@ -49,7 +53,9 @@ func TestDecodeA7R3(t *testing.T) {
 
 	start := time.Now()
 	t.Log(rw.gammaCurve)
-	readRaw14(buf, rw)
+	if _, err := readRaw14(buf, rw, nil); err != nil {
+		t.Error(err)
+	}
 	t.Log("processing duration:", time.Now().Sub(start))
 }
 
@@ -58,6 +64,7 @@ func TestProcessedPNG(t *testing.T) {
 	sample, err := os.Open(sampleName + ".ARW")
 	if err != nil {
 		t.Error(err)
+		t.FailNow()
 	}
 
 	rw, err := extractDetails(sample)
@@ -70,10 +77,18 @@ func TestProcessedPNG(t *testing.T) {
 
 	var rendered16bit *RGB14
 	if rw.rawType == raw14 {
-		rendered16bit = readRaw14(buf, rw)
+		rendered16bit, err = readRaw14(buf, rw, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
 	}
 	if rw.rawType == craw {
-		rendered16bit = readCRAW(buf, rw)
+		rendered16bit, err = readCRAW(buf, rw, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
 	}
 
 	wd, err := os.Getwd()
@@ -96,3 +111,139 @@ func TestProcessedPNG(t *testing.T) {
 
 	png.Encode(f, rendered16bit)
 }
+
+func TestUnpack12(t *testing.T) {
+	packed := []byte{0x23, 0x61, 0x45, 0xff, 0xff, 0xff}
+	expected := []uint16{0x123, 0x456, 0xfff, 0xfff}
+
+	unpacked := unpack12(packed, len(expected))
+	for i := range expected {
+		if unpacked[i] != expected[i] {
+			t.Errorf("sample %v: expected %#x, got %#x", i, expected[i], unpacked[i])
+		}
+	}
+
+	//An odd count ends with a sample in two bytes
+	unpacked = unpack12(packed[:5], 3)
+	if unpacked[0] != 0x123 || unpacked[1] != 0x456 || unpacked[2] != 0xfff {
+		t.Errorf("Unexpected samples of an odd count: %#x", unpacked)
+	}
+}
+
+func TestDecodeRaw12OddWidth(t *testing.T) {
+	//15x3 samples end in the middle of a pair
+	const width, height = 15, 3
+	samples := make([]uint16, width*height)
+	for i := range samples {
+		samples[i] = uint16(200 + i*29%3800)
+	}
+	packed := make([]byte, 0, (len(samples)*3+1)/2)
+	for i := 0; i < len(samples); i += 2 {
+		a := samples[i]
+		if i+1 == len(samples) {
+			packed = append(packed, byte(a), byte(a>>8&0x0f))
+			break
+		}
+		b := samples[i+1]
+		packed = append(packed, byte(a), byte(a>>8&0x0f)|byte(b&0x0f)<<4, byte(b>>4))
+	}
+
+	rs := bytes.NewReader(buildARW(t, width, height, raw12, 12, [4]uint16{128, 128, 128, 128}, packed))
+	rw, err := extractDetails(rs)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	data, err := readSensorData(io.NewSectionReader(rs, 0, rs.Size()), rw, nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if last := len(samples) - 1; data[last] != samples[last] {
+		t.Errorf("Expected the last sample %#x, got %#x", samples[last], data[last])
+	}
+
+	//A strip which ends before the last sample does not cover the frame
+	rs = bytes.NewReader(buildARW(t, width, height, raw12, 12, [4]uint16{128, 128, 128, 128}, packed[:len(packed)-1]))
+	if _, err := Decode(rs); err == nil {
+		t.Error("Expected the short strip to fail")
+	}
+}
+
+//TestDecodeRaw12 checks the samples of a packed 12 bit strip and that it renders like the same samples stored as raw14.
+func TestDecodeRaw12(t *testing.T) {
+	const width, height = 16, 8
+	samples := make([]uint16, width*height)
+	for i := range samples {
+		samples[i] = uint16(200 + i*29%3800)
+	}
+
+	//Two samples a, b are packed as a7..0, b3..0 a11..8, b11..4
+	packed := make([]byte, 0, len(samples)*3/2)
+	for i := 0; i < len(samples); i += 2 {
+		a, b := samples[i], samples[i+1]
+		packed = append(packed, byte(a), byte(a>>8&0x0f)|byte(b&0x0f)<<4, byte(b>>4))
+	}
+	raw12ARW := buildARW(t, width, height, raw12, 12, [4]uint16{128, 128, 129, 130}, packed)
+
+	rs := bytes.NewReader(raw12ARW)
+	rw, err := extractDetails(rs)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if rw.rawType != raw12 {
+		t.Error("Expected raw12, got:", rw.rawType)
+	}
+	data, err := readSensorData(io.NewSectionReader(rs, 0, rs.Size()), rw, nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i, v := range samples {
+		if data[i] != v {
+			t.Errorf("sample %v: expected %#x, got %#x", i, v, data[i])
+			break
+		}
+	}
+
+	//The raw14 pipeline is the reference, with samples and black levels shifted in to 14 bits
+	samples14 := make([]uint16, len(samples))
+	for i, v := range samples {
+		samples14[i] = v << 2
+	}
+	strip := make([]byte, len(samples14)*2)
+	for i, v := range samples14 {
+		binary.LittleEndian.PutUint16(strip[i*2:], v)
+	}
+	raw14ARW := buildARW(t, width, height, raw14, 14, [4]uint16{512, 512, 516, 520}, strip)
+
+	expected, err := Decode(bytes.NewReader(raw14ARW))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	rendered, err := Decode(bytes.NewReader(raw12ARW))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	compareImages(t, expected, rendered)
+}
+
+func compareImages(t *testing.T, expected, actual image.Image) {
+	if expected.Bounds() != actual.Bounds() {
+		t.Error("Bounds differ:", expected.Bounds(), actual.Bounds())
+		return
+	}
+	for y := expected.Bounds().Min.Y; y < expected.Bounds().Max.Y; y++ {
+		for x := expected.Bounds().Min.X; x < expected.Bounds().Max.X; x++ {
+			er, eg, eb, _ := expected.At(x, y).RGBA()
+			ar, ag, ab, _ := actual.At(x, y).RGBA()
+			if er != ar || eg != ag || eb != ab {
+				t.Errorf("Pixel (%v,%v) differs: expected %v %v %v, got %v %v %v", x, y, er, eg, eb, ar, ag, ab)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,321 @@
+//Command arwconvert renders ARW files to JPEG, PNG or TIFF.
+//
+//	arwconvert [-format jpeg|png|tiff] [-out dir] [-size pixels] [-colorspace srgb|adobergb|prophoto]
+//	           [-wb asshot|daylight|unity] [-demosaic nearest|bilinear|vng|ahd] [-threads n] file|directory...
+//
+//Directories are searched recursively for .ARW files and converted one after another, -threads sets the goroutines
+//decoding each file. Renders are written next to their source unless -out is given,
+//files whose render is newer than the source are skipped unless -force is given. A file which fails to convert is
+//reported and the remaining files are converted, as are paths which can not be read. The exit status is 1 when any file failed.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tobiash/arw"
+)
+
+//settings are the parsed flags shared by every conversion.
+type settings struct {
+	format  string
+	out     string
+	size    int
+	quality int
+	force   bool
+	decode  arw.Options
+	tiff    arw.TIFFOptions
+}
+
+//extensions maps an output format to the extension of its files.
+var extensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"tiff": ".tif",
+}
+
+var colorSpaces = map[string]arw.ColorProfile{
+	"srgb":     arw.SRGB,
+	"adobergb": arw.AdobeRGB,
+	"prophoto": arw.ProPhotoRGB,
+}
+
+var whiteBalances = map[string]arw.WhiteBalanceMode{
+	"asshot":   arw.WBAsShot,
+	"daylight": arw.WBDaylight,
+	"unity":    arw.WBUnity,
+}
+
+var demosaicers = map[string]arw.Demosaicer{
+	"nearest":  arw.Nearest{},
+	"bilinear": arw.Bilinear{},
+	"vng":      arw.VNG{},
+	"ahd":      arw.AHD{},
+}
+
+//job is a single file to convert.
+type job struct {
+	src string
+	dst string
+}
+
+//jobs lists the ARW files named by args, directories are searched recursively.
+//With an output directory the layout below a directory argument is kept. A path which can not be read is
+//reported in the errors and the remaining paths are still listed.
+func jobs(args []string, s settings) ([]job, []error) {
+	var list []job
+	var errs []error
+	ext := extensions[s.format]
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !info.IsDir() {
+			list = append(list, job{arg, outputPath(arg, filepath.Base(arg), s.out, ext)})
+			continue
+		}
+		filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				//Walk skips a directory which can not be read after reporting it here
+				errs = append(errs, err)
+				return nil
+			}
+			if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".arw") {
+				return nil
+			}
+			rel, err := filepath.Rel(arg, path)
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			list = append(list, job{path, outputPath(path, rel, s.out, ext)})
+			return nil
+		})
+	}
+	return list, errs
+}
+
+//outputPath replaces the extension of src, the render is placed at rel below out when out is set.
+func outputPath(src, rel, out, ext string) string {
+	if out != "" {
+		src = filepath.Join(out, rel)
+	}
+	return strings.TrimSuffix(src, filepath.Ext(src)) + ext
+}
+
+//upToDate reports whether dst exists and is not older than src.
+func upToDate(src, dst string) bool {
+	in, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	out, err := os.Stat(dst)
+	return err == nil && !out.ModTime().Before(in.ModTime())
+}
+
+//convert renders a single file. The render is written to a temporary file first so an interrupted
+//conversion is not taken as done by the next run.
+func convert(j job, s settings) error {
+	in, err := os.Open(j.src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	img, err := arw.DecodeWithOptions(in, &s.decode)
+	if err != nil {
+		return err
+	}
+	if s.size > 0 {
+		img = fit(img, s.size)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.dst), 0755); err != nil {
+		return err
+	}
+	tmp := j.dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	//No partial render is left behind when encoding fails
+	renamed := false
+	defer func() {
+		if !renamed {
+			out.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	err = encode(out, img, in, s)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.dst); err != nil {
+		return err
+	}
+	renamed = true
+	return nil
+}
+
+func encode(w io.Writer, img image.Image, src io.ReaderAt, s settings) error {
+	switch s.format {
+	case "png":
+		return png.Encode(w, img)
+	case "tiff":
+		opts := s.tiff
+		opts.Source = src
+		return arw.EncodeTIFF(w, img, &opts)
+	default:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: s.quality})
+	}
+}
+
+//fit scales img down by averaging boxes of pixels so that its longer side is at most size, smaller images are returned as they are.
+func fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	//at reads a pixel relative to the bounds, decoded images are read from their pixels as At allocates for every pixel
+	at := func(x, y int) (uint32, uint32, uint32) {
+		r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+		return r, g, bl
+	}
+	if src, ok := img.(*arw.RGB14); ok {
+		at = func(x, y int) (uint32, uint32, uint32) {
+			r, g, bl, _ := src.Pix[y*src.Stride+x].RGBA()
+			return r, g, bl
+		}
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb := at(sx, sy)
+					r, g, bl, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), 0xffff})
+		}
+	}
+	return dst
+}
+
+//parseSettings checks the flag values against the supported formats and options.
+func parseSettings(format, out, colorSpace, wb, demosaic string, size, threads, quality int, force bool) (settings, error) {
+	s := settings{format: strings.ToLower(format), out: out, size: size, quality: quality, force: force}
+	if s.format == "jpg" {
+		s.format = "jpeg"
+	}
+	if s.format == "tif" {
+		s.format = "tiff"
+	}
+	if _, ok := extensions[s.format]; !ok {
+		return s, errors.New("unsupported format: " + format)
+	}
+	cs, ok := colorSpaces[strings.ToLower(colorSpace)]
+	if !ok {
+		return s, errors.New("unsupported colour space: " + colorSpace)
+	}
+	if cs != arw.SRGB && s.format != "tiff" {
+		return s, errors.New("colour space " + cs.String() + " needs the tiff format, which embeds the profile")
+	}
+	mode, ok := whiteBalances[strings.ToLower(wb)]
+	if !ok {
+		return s, errors.New("unsupported white balance: " + wb)
+	}
+	d, ok := demosaicers[strings.ToLower(demosaic)]
+	if !ok {
+		return s, errors.New("unsupported demosaic algorithm: " + demosaic)
+	}
+	if size < 0 || threads < 0 || quality < 1 || quality > 100 {
+		return s, errors.New("size and threads can not be negative and quality has to be within 1 and 100")
+	}
+
+	s.tiff.ColorSpace = cs
+	s.decode = arw.Options{Demosaic: d, Workers: threads, WhiteBalance: mode}
+	return s, nil
+}
+
+func main() {
+	format := flag.String("format", "jpeg", "output format: jpeg, png or tiff")
+	out := flag.String("out", "", "directory to write renders to, next to the source when empty")
+	size := flag.Int("size", 0, "longest side of the render in pixels, 0 keeps the full size")
+	colorSpace := flag.String("colorspace", "srgb", "colour space of tiff renders: srgb, adobergb or prophoto")
+	wb := flag.String("wb", "asshot", "white balance: asshot, daylight or unity")
+	demosaic := flag.String("demosaic", "bilinear", "demosaic algorithm: nearest, bilinear, vng or ahd")
+	threads := flag.Int("threads", 0, "goroutines used to decode each file, 0 uses all processors")
+	quality := flag.Int("quality", 90, "JPEG quality")
+	force := flag.Bool("force", false, "convert files which already have an up to date render")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: arwconvert [flags] file|directory...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	s, err := parseSettings(*format, *out, *colorSpace, *wb, *demosaic, *size, *threads, *quality, *force)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	list, errs := jobs(flag.Args(), s)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	var converted, skipped int
+	failed := len(errs)
+	for _, j := range list {
+		if !s.force && upToDate(j.src, j.dst) {
+			skipped++
+			continue
+		}
+		if err := convert(j, s); err != nil {
+			fmt.Fprintln(os.Stderr, j.src+": "+err.Error())
+			failed++
+			continue
+		}
+		fmt.Println(j.src, "->", j.dst)
+		converted++
+	}
+	fmt.Printf("%v converted, %v skipped, %v failed\n", converted, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
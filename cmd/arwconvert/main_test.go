@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/tobiash/arw"
+)
+
+func TestParseSettings(t *testing.T) {
+	s, err := parseSettings("TIF", "", "prophoto", "daylight", "ahd", 1024, 2, 90, false)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if s.format != "tiff" || s.tiff.ColorSpace != arw.ProPhotoRGB || s.decode.WhiteBalance != arw.WBDaylight || s.decode.Workers != 2 {
+		t.Errorf("Unexpected settings: %+v", s)
+	}
+	if _, ok := s.decode.Demosaic.(arw.AHD); !ok {
+		t.Error("Expected AHD, got:", s.decode.Demosaic)
+	}
+
+	for _, args := range [][5]string{
+		{"gif", "", "srgb", "asshot", "bilinear"},
+		{"jpeg", "", "adobergb", "asshot", "bilinear"},
+		{"png", "", "srgb", "auto", "bilinear"},
+		{"png", "", "srgb", "asshot", "lanczos"},
+	} {
+		if _, err := parseSettings(args[0], args[1], args[2], args[3], args[4], 0, 0, 90, false); err == nil {
+			t.Error("Expected an error for:", args)
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.ARW", "b.arw", "notes.txt", "day/c.ARW"} {
+		path := filepath.Join(dir, "in", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//A missing path is reported and the others are still listed
+	s := settings{format: "png", out: filepath.Join(dir, "out")}
+	list, errs := jobs([]string{filepath.Join(dir, "missing.ARW"), filepath.Join(dir, "in")}, s)
+	if len(errs) != 1 || !os.IsNotExist(errs[0]) {
+		t.Error("Expected the missing path to be reported, got:", errs)
+	}
+	var got []string
+	for _, j := range list {
+		rel, _ := filepath.Rel(dir, j.dst)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	expected := []string{"out/a.png", "out/b.png", "out/day/c.png"}
+	if len(got) != len(expected) {
+		t.Fatal("Unexpected jobs:", got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], got[i])
+		}
+	}
+
+	//A render newer than its source is skipped, an older one is converted again
+	src, dst := filepath.Join(dir, "in", "a.ARW"), filepath.Join(dir, "a.png")
+	if upToDate(src, dst) {
+		t.Error("Missing render taken as up to date")
+	}
+	if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	os.Chtimes(src, now, now)
+	os.Chtimes(dst, now.Add(time.Minute), now.Add(time.Minute))
+	if !upToDate(src, dst) {
+		t.Error("Newer render not taken as up to date")
+	}
+	os.Chtimes(dst, now.Add(-time.Minute), now.Add(-time.Minute))
+	if upToDate(src, dst) {
+		t.Error("Older render taken as up to date")
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewRGBA64(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if x%2 == 0 {
+				img.SetRGBA64(x, y, color.RGBA64{0xffff, 0xffff, 0xffff, 0xffff})
+			}
+		}
+	}
+	scaled := fit(img, 10)
+	if b := scaled.Bounds(); b.Dx() != 10 || b.Dy() != 5 {
+		t.Error("Expected 10x5, got:", b)
+	}
+	//Every box covers as many white as black columns
+	if r, _, _, _ := scaled.At(3, 2).RGBA(); r != 0x7fff {
+		t.Errorf("Expected a box average of 0x7fff, got %#x", r)
+	}
+	if fit(img, 100) != image.Image(img) {
+		t.Error("Smaller images should be returned unchanged")
+	}
+
+	//Decoded images are read from their pixels, 14 bit samples are scaled to 16 bit
+	raw := arw.NewRGB14(image.Rect(0, 0, 40, 20))
+	for i := range raw.Pix {
+		if i%2 == 0 {
+			raw.Pix[i].R, raw.Pix[i].G, raw.Pix[i].B = 0x3fff, 0x3fff, 0x3fff
+		}
+	}
+	if r, _, _, _ := fit(raw, 10).At(3, 2).RGBA(); r != 0x7ffe {
+		t.Errorf("Expected a box average of 0x7ffe, got %#x", r)
+	}
+}
+
+//testARW is a little endian raw14 ARW of 2x2 samples, IFD0 points to the raw IFD through SubIFDs.
+func testARW() []byte {
+	order := binary.LittleEndian
+	var out bytes.Buffer
+	out.WriteString("II")
+	binary.Write(&out, order, []uint16{42})
+	binary.Write(&out, order, []uint32{8})
+
+	entry := func(tag arw.IFDtag, typ arw.IFDtype, count, value uint32) {
+		binary.Write(&out, order, []uint16{uint16(tag), uint16(typ)})
+		binary.Write(&out, order, []uint32{count, value})
+	}
+	//IFD0 at 8 with 1 entry ends at 8+2+12+4 = 26, the raw IFD with 6 entries ends at 26+2+72+4 = 104 where the strip starts
+	binary.Write(&out, order, uint16(1))
+	entry(arw.SubIFDs, arw.LONG, 1, 26)
+	binary.Write(&out, order, uint32(0))
+	binary.Write(&out, order, uint16(6))
+	entry(arw.ImageWidth, arw.SHORT, 1, 2)
+	entry(arw.ImageHeight, arw.SHORT, 1, 2)
+	entry(arw.BitsPerSample, arw.SHORT, 1, 14)
+	entry(arw.StripOffsets, arw.LONG, 1, 104)
+	entry(arw.StripByteCounts, arw.LONG, 1, 8)
+	entry(arw.SonyRawFileType, arw.SHORT, 1, 0)
+	binary.Write(&out, order, uint32(0))
+	binary.Write(&out, order, []uint16{1000, 2000, 2000, 3000})
+	return out.Bytes()
+}
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.ARW")
+	if err := ioutil.WriteFile(src, testARW(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := parseSettings("png", "", "srgb", "asshot", "bilinear", 0, 1, 90, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := job{src, filepath.Join(dir, "a.png")}
+	if err := convert(j, s); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := os.Stat(j.dst); err != nil {
+		t.Error("Render not written:", err)
+	}
+
+	//A file which fails to decode leaves no render behind
+	if err := ioutil.WriteFile(src, testARW()[:100], 0644); err != nil {
+		t.Fatal(err)
+	}
+	j.dst = filepath.Join(dir, "b.png")
+	if err := convert(j, s); err == nil {
+		t.Error("Expected the truncated file to fail")
+	}
+	for _, path := range []string{j.dst, j.dst + ".tmp"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("Render written despite the failure:", path, err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+//Command arwinfo prints every IFD and tag of ARW and other TIFF based files.
+//
+//	arwinfo [-ifd IFD0,IFD0/Exif] [-tag Make,0x9400] [-json | -csv] [-full] file...
+//
+//IFDs are selected by their path in the tree, such as IFD0/Exif/MakerNote, and may use the patterns of path.Match.
+//Tags are selected by name or by number. The -json mode writes one object per line, -csv writes a header and one row per tag.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/tobiash/arw"
+)
+
+//maxTextValue is the length values are cut to in text mode unless -full is given.
+const maxTextValue = 96
+
+//record is a single tag of a file.
+type record struct {
+	File  string `json:"file"`
+	IFD   string `json:"ifd"`
+	Tag   string `json:"tag"`
+	ID    uint16 `json:"id"`
+	Type  string `json:"type"`
+	Count uint32 `json:"count"`
+	Value string `json:"value"`
+}
+
+var csvHeader = []string{"file", "ifd", "tag", "id", "type", "count", "value"}
+
+func (r record) csv() []string {
+	return []string{r.File, r.IFD, r.Tag, fmt.Sprintf("0x%04x", r.ID), r.Type, fmt.Sprint(r.Count), r.Value}
+}
+
+//filter selects IFDs and tags, empty lists select everything.
+type filter struct {
+	ifds []string
+	tags []string
+}
+
+//parseList splits a comma separated flag value.
+func parseList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (f filter) ifd(p string) bool {
+	if len(f.ifds) == 0 {
+		return true
+	}
+	for _, pattern := range f.ifds {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (f filter) tag(tag arw.IFDtag) bool {
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, t := range f.tags {
+		if id, err := strconv.ParseUint(t, 0, 16); err == nil {
+			if arw.IFDtag(id) == tag {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(t, tag.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+//records lists the tags of every IFD of the document read from r which pass the filter.
+//On error the tags read before it are returned, the first IFD below the chain which could not be read is
+//reported after the tags of all others.
+func records(name string, r io.ReadSeeker, f filter) ([]record, error) {
+	tree, err := arw.ReadTree(r)
+	if tree == nil {
+		return nil, err
+	}
+
+	var list []record
+	tree.Walk(func(n *arw.IFDNode) error {
+		if n.Err != nil && err == nil {
+			err = n.Err
+		}
+		if !f.ifd(n.Path) {
+			return nil
+		}
+		for i, fia := range n.IFD.FIA {
+			if !f.tag(fia.Tag) {
+				continue
+			}
+			value := n.IFD.FIAvals[i].String()
+			if fia.Type == arw.ASCII {
+				value = strings.TrimRight(value, "\x00")
+			}
+			list = append(list, record{
+				File:  name,
+				IFD:   n.Path,
+				Tag:   fia.Tag.String(),
+				ID:    uint16(fia.Tag),
+				Type:  fia.Type.String(),
+				Count: fia.Count,
+				Value: value,
+			})
+		}
+		return nil
+	})
+	return list, err
+}
+
+//printer writes records in one of the output modes.
+type printer interface {
+	print(r record) error
+	flush() error
+}
+
+type textPrinter struct {
+	w    io.Writer
+	full bool
+	file string
+	ifd  string
+}
+
+func (p *textPrinter) print(r record) error {
+	if r.File != p.file {
+		if _, err := fmt.Fprintln(p.w, r.File); err != nil {
+			return err
+		}
+		p.file, p.ifd = r.File, ""
+	}
+	if r.IFD != p.ifd {
+		if _, err := fmt.Fprintln(p.w, "  "+r.IFD); err != nil {
+			return err
+		}
+		p.ifd = r.IFD
+	}
+	value := r.Value
+	if !p.full && len(value) > maxTextValue {
+		value = value[:maxTextValue] + "..."
+	}
+	_, err := fmt.Fprintf(p.w, "    %-28s 0x%04x %-9s %6d  %s\n", r.Tag, r.ID, r.Type, r.Count, value)
+	return err
+}
+
+func (p *textPrinter) flush() error {
+	return nil
+}
+
+type jsonPrinter struct {
+	enc *json.Encoder
+}
+
+func (p jsonPrinter) print(r record) error {
+	return p.enc.Encode(r)
+}
+
+func (p jsonPrinter) flush() error {
+	return nil
+}
+
+type csvPrinter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (p *csvPrinter) print(r record) error {
+	if !p.header {
+		p.header = true
+		if err := p.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	return p.w.Write(r.csv())
+}
+
+func (p *csvPrinter) flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
+
+//dump prints the tags of a single file.
+func dump(p printer, name string, f filter) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	list, err := records(name, file, f)
+	for _, r := range list {
+		if perr := p.print(r); perr != nil {
+			return perr
+		}
+	}
+	return err
+}
+
+func main() {
+	ifds := flag.String("ifd", "", "comma separated IFD paths or patterns to print, such as IFD0,IFD0/Exif/*")
+	tags := flag.String("tag", "", "comma separated tag names or numbers to print")
+	asJSON := flag.Bool("json", false, "write one JSON object per tag")
+	asCSV := flag.Bool("csv", false, "write CSV with a header row")
+	full := flag.Bool("full", false, "do not shorten long values in text mode")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: arwinfo [flags] file...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || (*asJSON && *asCSV) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var p printer
+	switch {
+	case *asJSON:
+		p = jsonPrinter{json.NewEncoder(os.Stdout)}
+	case *asCSV:
+		p = &csvPrinter{w: csv.NewWriter(os.Stdout)}
+	default:
+		p = &textPrinter{w: os.Stdout, full: *full}
+	}
+
+	f := filter{ifds: parseList(*ifds), tags: parseList(*tags)}
+	status := 0
+	for _, name := range flag.Args() {
+		if err := dump(p, name, f); err != nil {
+			var perr *os.PathError
+			if errors.As(err, &perr) {
+				fmt.Fprintln(os.Stderr, err)
+			} else {
+				fmt.Fprintln(os.Stderr, name+": "+err.Error())
+			}
+			status = 1
+		}
+	}
+	if err := p.flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		status = 1
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/tobiash/arw"
+)
+
+//testTIFF is a little endian document with Make and Orientation in IFD0 and an Exif IFD with ISO.
+func testTIFF() []byte {
+	order := binary.LittleEndian
+	var out bytes.Buffer
+	out.WriteString("II")
+	binary.Write(&out, order, []uint16{42})
+	binary.Write(&out, order, []uint32{8})
+
+	entry := func(tag arw.IFDtag, typ arw.IFDtype, count, value uint32) {
+		binary.Write(&out, order, []uint16{uint16(tag), uint16(typ)})
+		binary.Write(&out, order, []uint32{count, value})
+	}
+	//IFD0 at 8 with 3 entries ends at 8+2+36+4 = 50, the Exif IFD follows and the Make string after that
+	binary.Write(&out, order, uint16(3))
+	entry(arw.Make, arw.ASCII, 5, 50+2+12+4)
+	entry(arw.Orientation, arw.SHORT, 1, 6)
+	entry(arw.ExifTag, arw.LONG, 1, 50)
+	binary.Write(&out, order, uint32(0))
+	binary.Write(&out, order, uint16(1))
+	entry(arw.ISOSpeedRatings, arw.SHORT, 1, 400)
+	binary.Write(&out, order, uint32(0))
+	out.WriteString("SONY\x00")
+	return out.Bytes()
+}
+
+func TestRecords(t *testing.T) {
+	all, err := records("test.ARW", bytes.NewReader(testTIFF()), filter{})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(all) != 4 {
+		t.Error("Expected 4 tags, got:", all)
+	}
+	if all[0].Tag != "Make" || all[0].Value != "SONY" || all[0].Type != "ASCII" {
+		t.Error("Unexpected first tag:", all[0])
+	}
+
+	for _, test := range []struct {
+		f        filter
+		expected []string
+	}{
+		{filter{ifds: []string{"IFD0/*"}}, []string{"ISOSpeedRatings=400"}},
+		{filter{tags: []string{"orientation", "0x8827"}}, []string{"Orientation=6", "ISOSpeedRatings=400"}},
+		{filter{ifds: []string{"IFD0"}, tags: []string{"34855"}}, nil},
+	} {
+		list, err := records("test.ARW", bytes.NewReader(testTIFF()), test.f)
+		if err != nil {
+			t.Error(err)
+		}
+		var got []string
+		for _, r := range list {
+			got = append(got, r.Tag+"="+r.Value)
+		}
+		if strings.Join(got, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("%+v: expected %v, got %v", test.f, test.expected, got)
+		}
+	}
+}
+
+func TestCSVPrinter(t *testing.T) {
+	list, err := records("test.ARW", bytes.NewReader(testTIFF()), filter{tags: []string{"Make"}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	var out bytes.Buffer
+	p := &csvPrinter{w: csv.NewWriter(&out)}
+	for _, r := range list {
+		p.print(r)
+	}
+	if err := p.flush(); err != nil {
+		t.Error(err)
+	}
+	expected := "file,ifd,tag,id,type,count,value\ntest.ARW,IFD0,Make,0x010f,ASCII,5,SONY\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,90 @@
+package arw
+
+import "math"
+
+//Linear sRGB to CIE XYZ with a D65 white point, IEC 61966-2-1.
+var sRGBToXYZ = [3][3]float64{
+	{0.4124564, 0.3575761, 0.1804375},
+	{0.2126729, 0.7151522, 0.0721750},
+	{0.0193339, 0.1191920, 0.9503041},
+}
+
+var identity3 = [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+func mul3(a, b [3][3]float64) [3][3]float64 {
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+//cameraToSRGB returns the matrix from white balanced camera RGB to linear sRGB.
+//The ColorMatrix rows sum to 1024 and map camera RGB on to the sRGB primaries, so it is used as it is. Files without one are taken to be sRGB.
+func cameraToSRGB(colorMatrix [9]int16) [3][3]float64 {
+	if colorMatrix == [9]int16{} {
+		return identity3
+	}
+
+	var m [3][3]float64
+	for i := range colorMatrix {
+		m[i/3][i%3] = float64(colorMatrix[i]) / 1024
+	}
+	return m
+}
+
+//cameraToXYZ returns the matrix from white balanced camera RGB to XYZ, as needed by the DNG colour tags.
+func cameraToXYZ(colorMatrix [9]int16) [3][3]float64 {
+	return mul3(sRGBToXYZ, cameraToSRGB(colorMatrix))
+}
+
+//nearIdentity3 reports whether m changes no 14 bit value by more than a fraction of a code value.
+func nearIdentity3(m [3][3]float64) bool {
+	for i := range m {
+		for j := range m[i] {
+			if math.Abs(m[i][j]-identity3[i][j]) > 1e-6 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//applyColorMatrix converts every pixel with m, rounding and clipping the result to the 14 bit range.
+func applyColorMatrix(img *RGB14, m [3][3]float64) {
+	if nearIdentity3(m) {
+		return
+	}
+
+	for i := range img.Pix {
+		r, g, b := float64(img.Pix[i].R), float64(img.Pix[i].G), float64(img.Pix[i].B)
+		img.Pix[i].R = clamp14(int32(math.Round(m[0][0]*r + m[0][1]*g + m[0][2]*b)))
+		img.Pix[i].G = clamp14(int32(math.Round(m[1][0]*r + m[1][1]*g + m[1][2]*b)))
+		img.Pix[i].B = clamp14(int32(math.Round(m[2][0]*r + m[2][1]*g + m[2][2]*b)))
+	}
+}
+
+//invert3 returns the inverse of m, singular matrices result in the identity.
+func invert3(m [3][3]float64) [3][3]float64 {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det == 0 {
+		return identity3
+	}
+
+	var inv [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			//Cofactor of the transposed element
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			inv[i][j] = (m[a][c]*m[b][d] - m[a][d]*m[b][c]) / det
+		}
+	}
+	return inv
+}
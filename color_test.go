@@ -0,0 +1,61 @@
+package arw
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCameraToSRGBIdentity(t *testing.T) {
+	if m := cameraToSRGB([9]int16{}); m != identity3 {
+		t.Errorf("Expected identity without a ColorMatrix, got: %v", m)
+	}
+
+	//An identity ColorMatrix must leave pixels alone
+	m := cameraToSRGB([9]int16{1024, 0, 0, 0, 1024, 0, 0, 0, 1024})
+	if m != identity3 {
+		t.Errorf("Expected identity for an identity ColorMatrix, got: %v", m)
+	}
+	img := NewRGB14(image.Rect(0, 0, 1, 1))
+	img.Pix[0] = pixel16{R: 0x3fff, G: 8191, B: 1}
+	applyColorMatrix(img, m)
+	if img.Pix[0] != (pixel16{R: 0x3fff, G: 8191, B: 1}) {
+		t.Error("Identity changed a pixel:", img.Pix[0])
+	}
+}
+
+func TestApplyColorMatrixRounds(t *testing.T) {
+	img := NewRGB14(image.Rect(0, 0, 2, 1))
+	img.Pix[0] = pixel16{R: 3, G: 3, B: 3}
+	img.Pix[1] = pixel16{R: 1, G: 1, B: 1}
+	applyColorMatrix(img, [3][3]float64{{1.5, 0, 0}, {0, 0.49, 0}, {0, 0, 0.5}})
+	if img.Pix[0] != (pixel16{R: 5, G: 1, B: 2}) || img.Pix[1] != (pixel16{R: 2, G: 0, B: 1}) {
+		t.Error("Expected rounded values, got:", img.Pix)
+	}
+}
+
+func TestApplyColorMatrix(t *testing.T) {
+	//Rows sum to 1024, so neutral colours stay neutral
+	colorMatrix := [9]int16{1767, -643, -100, -190, 1451, -237, -37, -469, 1530}
+
+	img := NewRGB14(image.Rect(0, 0, 2, 1))
+	img.Pix[0] = pixel16{R: 4000, G: 4000, B: 4000}
+	img.Pix[1] = pixel16{R: 0x3fff, G: 0, B: 0}
+	applyColorMatrix(img, cameraToSRGB(colorMatrix))
+
+	grey := img.Pix[0]
+	if absDiff(grey.R, 4000) > 2 || absDiff(grey.G, 4000) > 2 || absDiff(grey.B, 4000) > 2 {
+		t.Error("Expected grey to stay neutral, got:", grey)
+	}
+
+	red := img.Pix[1]
+	if red.R != 0x3fff || red.G != 0 || red.B != 0 {
+		t.Error("Expected saturated red to be clipped, got:", red)
+	}
+}
+
+func absDiff(a, b uint16) uint16 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,65 @@
+package arw
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestCRAWDamagedBlock(t *testing.T) {
+	const width, height = 64, 6
+	rng := rand.New(rand.NewSource(9))
+	buf := randomCRAW(rng, width, height)
+	rw := rawDetails{width: width, height: height, offset: 1000}
+
+	//Give the odd block of the second group on line 3 the same minimum and maximum position
+	const x, y = 32, 3
+	base := y*width + x + pixelBlockSize
+	head := binary.LittleEndian.Uint32(buf[base:])
+	head = head&^(0x0f<<26) | (head>>22&0x0f)<<26
+	binary.LittleEndian.PutUint32(buf[base:], head)
+
+	_, err := unpackCRAW(buf, rw, nil)
+	blockErr, ok := err.(*BlockError)
+	if !ok {
+		t.Error("Expected a *BlockError, got:", err)
+		t.FailNow()
+	}
+	if blockErr.X != x || blockErr.Y != y || blockErr.Offset != y*width+x || blockErr.StripOffset != 1000 || blockErr.Err != ErrBlockIndex {
+		t.Errorf("Unexpected block error: %+v", blockErr)
+	}
+	if !errors.Is(err, ErrBlockIndex) || errors.Is(err, ErrBlockRange) {
+		t.Error("errors.Is does not see the cause of:", err)
+	}
+
+	var reported []*BlockError
+	opts := &Options{Lenient: true, Damaged: func(e *BlockError) { reported = append(reported, e) }}
+	data, err := unpackCRAW(buf, rw, opts)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(reported) != 1 || reported[0].X != x || reported[0].Y != y {
+		t.Errorf("Expected the damaged block to be reported once, got: %v", reported)
+	}
+	for i := 0; i < 2*pixelBlockSize; i++ {
+		if data[y*width+x+i] != data[(y-2)*width+x+i] {
+			t.Errorf("pixel (%v,%v) was not concealed from two lines up", x+i, y)
+			break
+		}
+	}
+}
+
+func TestCRAWTruncated(t *testing.T) {
+	const width, height = 64, 4
+	rng := rand.New(rand.NewSource(10))
+	buf := randomCRAW(rng, width, height)
+	rw := rawDetails{width: width, height: height}
+
+	_, err := unpackCRAW(buf[:len(buf)-40], rw, nil)
+	if blockErr, ok := err.(*BlockError); !ok || blockErr.Err != io.ErrUnexpectedEOF || blockErr.Y != height-1 || blockErr.X != 0 {
+		t.Error("Expected a truncated block on the last line, got:", err)
+	}
+}
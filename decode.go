@@ -0,0 +1,203 @@
+package arw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+)
+
+//Both ARW byte orders share the TIFF magic, ARW files in the wild are all little endian.
+//The magic is the one of every little endian TIFF, image.Decode picks the first registered format matching it so a program
+//which also imports a TIFF decoder may have plain TIFFs routed here. Those fail fast with ErrNotARW, import the TIFF decoder
+//first or use its Decode directly when both are needed.
+const arwMagic = "II*\x00"
+
+//ErrNotARW is returned through image.Decode for TIFF documents which are not Sony raw files.
+var ErrNotARW = errors.New("not a Sony ARW file")
+
+func init() {
+	image.RegisterFormat("arw", arwMagic, decode, decodeConfig)
+}
+
+//Options are the parameters used to render the raw sensor data.
+type Options struct {
+	//Demosaic interpolates the missing colours of every pixel, Bilinear is used when nil.
+	Demosaic Demosaicer
+	//Workers is the number of goroutines used to decode a frame, GOMAXPROCS is used when zero.
+	Workers int
+	//Lenient conceals damaged compressed blocks instead of failing the decode.
+	Lenient bool
+	//Damaged is called for every block concealed in lenient mode.
+	Damaged func(*BlockError)
+	//WhiteBalance selects the multipliers applied to the raw channels, the as shot balance is used by default.
+	WhiteBalance WhiteBalanceMode
+}
+
+//WhiteBalanceMode selects where the white balance of a render comes from.
+type WhiteBalanceMode int
+
+const (
+	//WBAsShot uses the WB_RGGBLevels recorded with the shot.
+	WBAsShot WhiteBalanceMode = iota
+	//WBDaylight uses the WB_RGBLevelsDaylight preset of the SR2SubIFD, files without it fall back to the as shot balance.
+	WBDaylight
+	//WBUnity leaves the camera channels unscaled.
+	WBUnity
+)
+
+//Decode reads an ARW file and renders the raw sensor data to an image using the default options.
+func Decode(r io.ReaderAt) (image.Image, error) {
+	return DecodeWithOptions(r, nil)
+}
+
+//DecodeWithOptions reads an ARW file and renders the raw sensor data to an image, opts may be nil.
+//The size of r is taken from a Size or Stat method, as bytes.Reader, io.SectionReader and *os.File have. Offsets read from
+//readers without either are only checked by reading them.
+func DecodeWithOptions(r io.ReaderAt, opts *Options) (image.Image, error) {
+	rs, err := sizedReader(r)
+	if err != nil {
+		return nil, err
+	}
+	rw, err := extractDetails(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	//A nil *RGB14 must not be returned as a non nil image.Image
+	var img *RGB14
+	switch rw.rawType {
+	case crawLossless:
+		img, err = readCRAWLossless(rs, rw, opts)
+	case raw14, raw12, craw:
+		var buf []byte
+		if buf, err = readStrip(rs, rw); err != nil {
+			return nil, err
+		}
+		switch rw.rawType {
+		case raw14:
+			img, err = readRaw14(buf, rw, opts)
+		case raw12:
+			img, err = readRaw12(buf, rw, opts)
+		default:
+			img, err = readCRAW(buf, rw, opts)
+		}
+	default:
+		return nil, errors.New("unsupported raw type: " + fmt.Sprint(rw.rawType))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+//DecodeConfig returns the dimensions and colour model of the image Decode would render, without decoding the raw data.
+func DecodeConfig(r io.ReaderAt) (image.Config, error) {
+	rs, err := sizedReader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	rw, err := extractDetails(rs)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: color.RGBA64Model,
+		Width:      int(rw.width),
+		Height:     int(rw.height),
+	}, nil
+}
+
+//decode adapts Decode to the io.Reader based signature image.RegisterFormat expects.
+func decode(r io.Reader) (image.Image, error) {
+	ra, err := readerAt(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isARW(ra) {
+		return nil, ErrNotARW
+	}
+	return Decode(ra)
+}
+
+func decodeConfig(r io.Reader) (image.Config, error) {
+	ra, err := readerAt(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if !isARW(ra) {
+		return image.Config{}, ErrNotARW
+	}
+	return DecodeConfig(ra)
+}
+
+//isARW reports whether IFD0 names Sony as Make. DNGPrivateData is no sign of an ARW, Adobe DNGs carry it as well.
+func isARW(r io.ReaderAt) bool {
+	rs, err := sizedReader(r)
+	if err != nil {
+		return false
+	}
+	f, err := NewFile(rs)
+	if err != nil {
+		return false
+	}
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return false
+	}
+	return sonyMake(ifd0)
+}
+
+//sonyMake reports whether the Make entry of the IFD is SONY.
+func sonyMake(ifd EXIFIFD) bool {
+	v, ok := ifd.Lookup(Make)
+	if !ok {
+		return false
+	}
+	text, err := v.Text()
+	return err == nil && strings.EqualFold(strings.TrimSpace(text), "SONY")
+}
+
+//TIFF offsets point anywhere in the file, so a plain reader or one of unknown size has to be buffered entirely.
+func readerAt(r io.Reader) (io.ReaderAt, error) {
+	if ra, ok := r.(io.ReaderAt); ok && knownSize(ra) {
+		return ra, nil
+	}
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
+}
+
+//knownSize reports whether sizedReader can take the size of r from it.
+func knownSize(r io.ReaderAt) bool {
+	switch r.(type) {
+	case interface{ Size() int64 }, interface{ Stat() (os.FileInfo, error) }:
+		return true
+	}
+	return false
+}
+
+//sizedReader covers r with a section of its real size, so offsets read from the file are checked against its end.
+//A reader of unknown size is covered entirely, reads past its end fail instead.
+func sizedReader(r io.ReaderAt) (*io.SectionReader, error) {
+	switch sized := r.(type) {
+	case interface{ Size() int64 }:
+		return io.NewSectionReader(r, 0, sized.Size()), nil
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := sized.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return io.NewSectionReader(r, 0, info.Size()), nil
+	}
+	return io.NewSectionReader(r, 0, math.MaxInt64), nil
+}
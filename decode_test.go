@@ -0,0 +1,180 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	samplename := samples[raw14][0]
+	testARW, err := os.Open(samplename + ".ARW")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer testARW.Close()
+
+	cfg, err := DecodeConfig(testARW)
+	if err != nil {
+		t.Error(err)
+	}
+
+	img, err := Decode(testARW)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	if img.Bounds().Dx() != cfg.Width || img.Bounds().Dy() != cfg.Height {
+		t.Error("DecodeConfig and Decode disagree on dimensions:", cfg.Width, cfg.Height, img.Bounds())
+	}
+}
+
+func TestImageDecode(t *testing.T) {
+	samplename := samples[craw][0]
+	testARW, err := os.Open(samplename + ".ARW")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer testARW.Close()
+
+	_, format, err := image.Decode(testARW)
+	if err != nil {
+		t.Error(err)
+	}
+	if format != "arw" {
+		t.Error("Expected arw format, got:", format)
+	}
+}
+
+func TestImageDecodeNotARW(t *testing.T) {
+	const width, height = 16, 8
+	arw := buildRaw14ARW(t, width, height, make([]uint16, width*height))
+	if _, format, err := image.DecodeConfig(bytes.NewReader(arw)); err != nil || format != "arw" {
+		t.Error("Expected the Sony fixture to be decoded as arw:", format, err)
+	}
+
+	plain := buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{Make, ASCII, []byte("Canon\x00")}, {ImageWidth, SHORT, []uint16{16}}}})
+	if _, _, err := image.DecodeConfig(bytes.NewReader(plain)); err != ErrNotARW {
+		t.Error("Expected ErrNotARW for a plain TIFF, got:", err)
+	}
+	if _, _, err := image.Decode(bytes.NewReader(plain)); err != ErrNotARW {
+		t.Error("Expected ErrNotARW for a plain TIFF, got:", err)
+	}
+
+	//Adobe DNGs have DNGPrivateData too
+	dng := buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{Make, ASCII, []byte("Canon\x00")}, {DNGPrivateData, BYTE, []byte("Adobe\x00MakN")}}})
+	if _, _, err := image.DecodeConfig(bytes.NewReader(dng)); err != ErrNotARW {
+		t.Error("Expected ErrNotARW for a DNG, got:", err)
+	}
+}
+
+//readerAtOnly hides every method of the reader but ReadAt.
+type readerAtOnly struct {
+	r io.ReaderAt
+}
+
+func (r readerAtOnly) ReadAt(p []byte, off int64) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+func TestDecodeUnsizedReader(t *testing.T) {
+	const width, height = 16, 8
+	arw := buildRaw14ARW(t, width, height, make([]uint16, width*height))
+	img, err := Decode(readerAtOnly{bytes.NewReader(arw)})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Error("Unexpected bounds:", b)
+	}
+
+	//Without a size the short strip is only found by reading it
+	if _, err := Decode(readerAtOnly{bytes.NewReader(arw[:len(arw)-2])}); err == nil {
+		t.Error("Expected an error for a truncated strip")
+	}
+}
+
+//TestRenderConcurrent renders frames with different tone curves in parallel, run with -race to catch shared decoder state.
+func TestRenderConcurrent(t *testing.T) {
+	const width, height = 32, 24
+	curves := [][5]uint16{
+		{8000, 10400, 12900, 14100, 0x3fff},
+		{4000, 8000, 12000, 14000, 0x3fff},
+		{10000, 12000, 13500, 15000, 0x3fff},
+	}
+
+	rng := rand.New(rand.NewSource(3))
+	frames := make([][]uint16, len(curves))
+	details := make([]rawDetails, len(curves))
+	for i, curve := range curves {
+		frames[i] = make([]uint16, width*height)
+		for j := range frames[i] {
+			frames[i][j] = uint16(rng.Intn(0x4000))
+		}
+		details[i] = rawDetails{width: width, height: height, gammaCurve: curve}
+		details[i].blackLevel = [4]uint16{512, 512, 512, 512}
+		details[i].WhiteBalance = [4]int16{2400, 1024, 1024, 1600}
+	}
+
+	expected := make([]*RGB14, len(curves))
+	for i := range curves {
+		data := append([]uint16(nil), frames[i]...)
+		img, err := render(data, details[i], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[i] = img
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, len(curves)*8)
+	for n := 0; n < 8; n++ {
+		for i := range curves {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data := append([]uint16(nil), frames[i]...)
+				img, err := render(data, details[i], nil)
+				if err != nil {
+					errs <- err.Error()
+					return
+				}
+				for j := range img.Pix {
+					if img.Pix[j] != expected[i].Pix[j] {
+						errs <- fmt.Sprintf("curve %v pixel %v: expected %v, got %v", i, j, expected[i].Pix[j], img.Pix[j])
+						return
+					}
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDecodeShortStrip(t *testing.T) {
+	const width, height = 16, 8
+	for _, samples := range []int{width, width*height - 1} {
+		arw := buildRaw14ARW(t, width, height, make([]uint16, samples))
+		if _, err := Decode(bytes.NewReader(arw)); err == nil {
+			t.Error("Expected an error for a strip of", samples, "samples")
+		}
+	}
+	arw := buildRaw14ARW(t, width, height, make([]uint16, width*height))
+	if _, err := Decode(bytes.NewReader(arw)); err != nil {
+		t.Error(err)
+	}
+}
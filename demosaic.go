@@ -0,0 +1,169 @@
+package arw
+
+import (
+	"image"
+)
+
+//Colours used in a CFA pattern, numbered like the TIFF/EP CFAPattern tag.
+const (
+	cfaRed   = 0
+	cfaGreen = 1
+	cfaBlue  = 2
+)
+
+//defaultCFAPattern is used for files which do not carry a CFAPattern2 tag.
+var defaultCFAPattern = [4]uint8{cfaRed, cfaGreen, cfaGreen, cfaBlue}
+
+//CFA is a single channel frame of sensor data behind a colour filter array with a 2x2 repeating pattern.
+type CFA struct {
+	Pix []uint16
+	// Stride is the Pix stride between vertically adjacent pixels.
+	Stride int
+	// Rect is the frame's bounds.
+	Rect image.Rectangle
+	// Pattern holds the colour of the top left 2x2 square in row major order, 0 is red, 1 is green and 2 is blue.
+	Pattern [4]uint8
+}
+
+//Color returns the colour of the filter in front of the pixel at x, y.
+func (c *CFA) Color(x, y int) uint8 {
+	return c.Pattern[((y-c.Rect.Min.Y)&1)*2+((x-c.Rect.Min.X)&1)]
+}
+
+func (c *CFA) at(x, y int) uint16 {
+	return c.Pix[(y-c.Rect.Min.Y)*c.Stride+(x-c.Rect.Min.X)]
+}
+
+//Demosaicer interpolates the two missing colours of every pixel in a CFA frame.
+type Demosaicer interface {
+	Demosaic(cfa *CFA) *RGB14
+}
+
+func setChannel(p *pixel16, c uint8, v uint16) {
+	switch c {
+	case cfaRed:
+		p.R = v
+	case cfaGreen:
+		p.G = v
+	case cfaBlue:
+		p.B = v
+	}
+}
+
+func channel(p pixel16, c uint8) uint16 {
+	switch c {
+	case cfaRed:
+		return p.R
+	case cfaGreen:
+		return p.G
+	default:
+		return p.B
+	}
+}
+
+//Nearest copies the missing colours from the pixels sharing the same 2x2 square, this is fast but causes zippering on edges.
+type Nearest struct{}
+
+func (n Nearest) Demosaic(cfa *CFA) *RGB14 {
+	img := NewRGB14(cfa.Rect)
+	n.demosaicRows(cfa, img, cfa.Rect.Min.Y, cfa.Rect.Max.Y)
+	return img
+}
+
+func (Nearest) demosaicRows(cfa *CFA, img *RGB14, y0, y1 int) {
+	for y := y0; y < y1; y++ {
+		for x := cfa.Rect.Min.X; x < cfa.Rect.Max.X; x++ {
+			var p pixel16
+			//The square is shifted back on the last odd row or column so it stays inside the frame
+			sx, sy := x-((x-cfa.Rect.Min.X)&1), y-((y-cfa.Rect.Min.Y)&1)
+			if sx+1 >= cfa.Rect.Max.X {
+				sx--
+			}
+			if sy+1 >= cfa.Rect.Max.Y {
+				sy--
+			}
+			for i := 3; i >= 0; i-- {
+				nx, ny := sx+(i&1), sy+(i>>1)
+				if nx >= cfa.Rect.Min.X && ny >= cfa.Rect.Min.Y {
+					setChannel(&p, cfa.Color(nx, ny), cfa.at(nx, ny))
+				}
+			}
+			setChannel(&p, cfa.Color(x, y), cfa.at(x, y))
+			img.set(x-cfa.Rect.Min.X, y-cfa.Rect.Min.Y, p)
+		}
+	}
+}
+
+//Bilinear averages the nearest pixels of each missing colour in the surrounding 3x3 square.
+type Bilinear struct{}
+
+func (b Bilinear) Demosaic(cfa *CFA) *RGB14 {
+	img := NewRGB14(cfa.Rect)
+	b.demosaicRows(cfa, img, cfa.Rect.Min.Y, cfa.Rect.Max.Y)
+	return img
+}
+
+func (Bilinear) demosaicRows(cfa *CFA, img *RGB14, y0, y1 int) {
+	for y := y0; y < y1; y++ {
+		for x := cfa.Rect.Min.X; x < cfa.Rect.Max.X; x++ {
+			img.set(x-cfa.Rect.Min.X, y-cfa.Rect.Min.Y, bilinearAt(cfa, x, y))
+		}
+	}
+}
+
+//bilinearAt interpolates a single pixel, it is also used for the borders of the more elaborate algorithms.
+func bilinearAt(cfa *CFA, x, y int) pixel16 {
+	var sum [3]uint32
+	var count [3]uint32
+	own := cfa.Color(x, y)
+
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if nx < cfa.Rect.Min.X || ny < cfa.Rect.Min.Y || nx >= cfa.Rect.Max.X || ny >= cfa.Rect.Max.Y {
+				continue
+			}
+			c := cfa.Color(nx, ny)
+			if c == own {
+				continue
+			}
+			sum[c] += uint32(cfa.at(nx, ny))
+			count[c]++
+		}
+	}
+
+	var p pixel16
+	for c := uint8(0); c < 3; c++ {
+		if count[c] > 0 {
+			setChannel(&p, c, uint16(sum[c]/count[c]))
+		}
+	}
+	setChannel(&p, own, cfa.at(x, y))
+	return p
+}
+
+//borderInterpolate fills a border of the given width with bilinear interpolation for the rows [y0, y1).
+func borderInterpolate(cfa *CFA, img *RGB14, border, y0, y1 int) {
+	r := cfa.Rect
+	for y := y0; y < y1; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if x == r.Min.X+border && y >= r.Min.Y+border && y < r.Max.Y-border {
+				x = r.Max.X - border
+				if x < r.Min.X+border {
+					x = r.Min.X + border
+				}
+			}
+			img.set(x-r.Min.X, y-r.Min.Y, bilinearAt(cfa, x, y))
+		}
+	}
+}
+
+func clamp14(v int32) uint16 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 0x3fff:
+		return 0x3fff
+	}
+	return uint16(v)
+}
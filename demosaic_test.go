@@ -0,0 +1,151 @@
+package arw
+
+import (
+	"image"
+	"testing"
+)
+
+var demosaicers = map[string]Demosaicer{
+	"Nearest":  Nearest{},
+	"Bilinear": Bilinear{},
+	"VNG":      VNG{},
+	"AHD":      AHD{},
+}
+
+//mosaic samples an image through a colour filter array.
+func mosaic(width, height int, pattern [4]uint8, colour func(x, y int) [3]uint16) *CFA {
+	cfa := &CFA{
+		Pix:     make([]uint16, width*height),
+		Stride:  width,
+		Rect:    image.Rect(0, 0, width, height),
+		Pattern: pattern,
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			cfa.Pix[y*width+x] = colour(x, y)[cfa.Color(x, y)]
+		}
+	}
+	return cfa
+}
+
+func TestDemosaicFlat(t *testing.T) {
+	flat := func(x, y int) [3]uint16 { return [3]uint16{1000, 2000, 3000} }
+	patterns := [][4]uint8{
+		{cfaRed, cfaGreen, cfaGreen, cfaBlue},
+		{cfaGreen, cfaRed, cfaBlue, cfaGreen},
+		{cfaBlue, cfaGreen, cfaGreen, cfaRed},
+		{cfaGreen, cfaBlue, cfaRed, cfaGreen},
+	}
+
+	for _, pattern := range patterns {
+		cfa := mosaic(37, 23, pattern, flat)
+		for name, d := range demosaicers {
+			img := d.Demosaic(cfa)
+			if img.Rect != cfa.Rect {
+				t.Errorf("%v: unexpected bounds %v", name, img.Rect)
+				continue
+			}
+			for y := 0; y < cfa.Rect.Dy(); y++ {
+				for x := 0; x < cfa.Rect.Dx(); x++ {
+					p := img.at(x, y)
+					if p.R != 1000 || p.G != 2000 || p.B != 3000 {
+						t.Errorf("%v %v: pixel (%v,%v) expected 1000 2000 3000, got %v %v %v", name, pattern, x, y, p.R, p.G, p.B)
+						x, y = cfa.Rect.Dx(), cfa.Rect.Dy()
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDemosaicEdge(t *testing.T) {
+	//A vertical edge between two colours, the nearest neighbour copy is expected to zipper along it.
+	edge := func(x, y int) [3]uint16 {
+		if x < 21 {
+			return [3]uint16{2000, 4000, 6000}
+		}
+		return [3]uint16{12000, 10000, 8000}
+	}
+	cfa := mosaic(40, 40, defaultCFAPattern, edge)
+
+	errors := make(map[string]uint64)
+	for name, d := range demosaicers {
+		img := d.Demosaic(cfa)
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 40; x++ {
+				p, want := img.at(x, y), edge(x, y)
+				for c, v := range [3]uint16{p.R, p.G, p.B} {
+					diff := int(v) - int(want[c])
+					if diff < 0 {
+						diff = -diff
+					}
+					errors[name] += uint64(diff)
+				}
+			}
+		}
+	}
+
+	for _, pair := range [][2]string{{"Bilinear", "Nearest"}, {"VNG", "Bilinear"}, {"AHD", "Bilinear"}} {
+		if errors[pair[0]] >= errors[pair[1]] {
+			t.Errorf("%v is expected to beat %v on a sharp edge, error %v vs %v", pair[0], pair[1], errors[pair[0]], errors[pair[1]])
+		}
+	}
+	t.Log(errors)
+}
+
+func TestDevelopCFAPattern(t *testing.T) {
+	var rw rawDetails
+	rw.width, rw.height = 2, 2
+	rw.cfaPattern = [4]uint8{cfaGreen, cfaRed, cfaBlue, cfaGreen}
+	rw.cfaPatternDim = [2]uint16{2, 2}
+	rw.blackLevel = [4]uint16{100, 200, 300, 400} //RGGB
+	rw.WhiteBalance = [4]int16{1024, 512, 512, 256}
+
+	cfa := develop([]uint16{1200, 1100, 1400, 1300}, rw, 1)
+	if cfa.Pattern != rw.cfaPattern {
+		t.Error("Expected the file's CFA pattern, got:", cfa.Pattern)
+	}
+
+	//G1 R / B G2
+	expected := []uint16{(1200 - 200) / 2, 1100 - 100, (1400 - 400) / 4, (1300 - 300) / 2}
+	for i := range expected {
+		if cfa.Pix[i] != expected[i] {
+			t.Errorf("site %v: expected %v, got %v", i, expected[i], cfa.Pix[i])
+		}
+	}
+}
+
+func TestWhiteBalanceMode(t *testing.T) {
+	var rw rawDetails
+	rw.WhiteBalance = [4]int16{2600, 1024, 1024, 1800}
+	for _, test := range []struct {
+		opts     *Options
+		daylight [4]int16
+		expected [4]int16
+	}{
+		{nil, [4]int16{2400, 1024, 1024, 1600}, rw.WhiteBalance},
+		{&Options{WhiteBalance: WBDaylight}, [4]int16{2400, 1024, 1024, 1600}, [4]int16{2400, 1024, 1024, 1600}},
+		{&Options{WhiteBalance: WBDaylight}, [4]int16{}, rw.WhiteBalance},
+		{&Options{WhiteBalance: WBUnity}, [4]int16{}, [4]int16{1, 1, 1, 1}},
+	} {
+		rw.daylight = test.daylight
+		if got := test.opts.whiteBalance(rw); got != test.expected {
+			t.Errorf("%+v: expected %v, got %v", test.opts, test.expected, got)
+		}
+	}
+}
+
+func TestCheckCFA(t *testing.T) {
+	if err := checkCFA([4]uint8{}, [2]uint16{}); err != nil {
+		t.Error("A missing pattern should default to RGGB:", err)
+	}
+	if err := checkCFA([4]uint8{cfaBlue, cfaGreen, cfaGreen, cfaRed}, [2]uint16{2, 2}); err != nil {
+		t.Error(err)
+	}
+	if err := checkCFA([4]uint8{cfaRed, cfaGreen, cfaGreen, cfaRed}, [2]uint16{2, 2}); err == nil {
+		t.Error("Expected an error for a pattern without blue")
+	}
+	if err := checkCFA([4]uint8{cfaRed, cfaGreen, cfaGreen, cfaBlue}, [2]uint16{4, 4}); err == nil {
+		t.Error("Expected an error for a 4x4 repeat pattern")
+	}
+}
@@ -0,0 +1,209 @@
+package arw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"math"
+	"strings"
+)
+
+//WriteDNG converts the ARW read from r in to a DNG written to w, opts may be nil.
+//The DNG holds the unprocessed CFA samples uncompressed in IFD0 together with the black and white level, a colour matrix and
+//neutral derived from the ColorMatrix and WB_RGGBLevels of the ARW, a copy of its Exif IFD and the embedded JPEG preview as SubIFD.
+//The makernote is not copied as its values are referenced by offsets in to the ARW. The size of r is taken as by DecodeWithOptions.
+func WriteDNG(w io.Writer, r io.ReaderAt, opts *Options) error {
+	rs, err := sizedReader(r)
+	if err != nil {
+		return err
+	}
+	rw, err := extractDetails(rs)
+	if err != nil {
+		return err
+	}
+	data, err := readSensorData(rs, rw, opts)
+	if err != nil {
+		return err
+	}
+	width, height := int(rw.width), int(rw.height)
+	if width == 0 || height == 0 || len(data) < width*height {
+		return errors.New("raw data does not cover the frame: " + fmt.Sprint(len(data), " samples for ", width, "x", height))
+	}
+
+	f, err := NewFile(io.NewSectionReader(r, 0, rs.Size()))
+	if err != nil {
+		return err
+	}
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return err
+	}
+	order := f.order
+	tw := newTIFFWriter(order)
+
+	strip := make([]byte, width*height*2)
+	for i, v := range data[:width*height] {
+		order.PutUint16(strip[i*2:], v)
+	}
+	stripOffset := tw.write(strip)
+
+	values := []entryValues{
+		{NewSubFileType, LONG, []uint32{0}},
+		{ImageWidth, LONG, []uint32{uint32(width)}},
+		{ImageHeight, LONG, []uint32{uint32(height)}},
+		{BitsPerSample, SHORT, []uint16{16}},
+		{Compression, SHORT, []uint16{compressionNone}},
+		{PhotometricInterpretation, SHORT, []uint16{photometricCFA}},
+		{StripOffsets, LONG, []uint32{stripOffset}},
+		{SamplesPerPixel, SHORT, []uint16{1}},
+		{RowsPerStrip, LONG, []uint32{uint32(height)}},
+		{StripByteCounts, LONG, []uint32{uint32(len(strip))}},
+		{PlanarConfiguration, SHORT, []uint16{1}},
+		{DNGVersion, BYTE, []byte{1, 4, 0, 0}},
+		{DNGBackwardVersion, BYTE, []byte{1, 1, 0, 0}},
+	}
+	values = append(values, dngColorEntries(rw)...)
+	values = append(values, entryValues{UniqueCameraModel, ASCII, uniqueCameraModel(ifd0)})
+
+	var preview, exif uint32
+	for _, fia := range ifd0.FIA {
+		switch fia.Tag {
+		case ExifTag:
+			if exif, err = writeExifCopy(tw, f, int64(fia.Offset)); err != nil {
+				return err
+			}
+		case JPEGInterchangeFormat:
+			if preview, err = writeDNGPreview(tw, f, ifd0); err != nil {
+				return err
+			}
+		}
+	}
+	if preview != 0 {
+		values = append(values, entryValues{SubIFDs, LONG, []uint32{preview}})
+	}
+	if exif != 0 {
+		values = append(values, entryValues{ExifTag, LONG, []uint32{exif}})
+	}
+
+	raw, err := newEntries(order, values)
+	if err != nil {
+		return err
+	}
+	copied, err := copyEntries(f, ifd0, func(tag IFDtag) bool { return !descriptiveTags[tag] })
+	if err != nil {
+		return err
+	}
+	raw = append(raw, copied...)
+
+	return tw.finish(w, tw.writeIFD(raw, 0))
+}
+
+//dngColorEntries describes the CFA layout and colour of the sensor data.
+func dngColorEntries(rw rawDetails) []entryValues {
+	pattern := rw.cfaPattern
+	if rw.cfaPatternDim == [2]uint16{} {
+		pattern = defaultCFAPattern
+	}
+	sites := rggbSites(pattern)
+	var black [4]uint16
+	for i, site := range sites {
+		black[i] = rw.blackLevel[site]
+	}
+
+	//The ColorMatrix of the ARW works on white balanced camera RGB, DNG expects a matrix from XYZ to camera RGB before
+	//white balance so the balance is folded in to the matrix and given as the neutral
+	balance := [3]float64{1, 1, 1}
+	neutral := []uint32{1, 1, 1, 1, 1, 1}
+	wb := rw.WhiteBalance
+	if wb[0] > 0 && wb[1] > 0 && wb[3] > 0 {
+		balance = [3]float64{float64(wb[0]) / float64(wb[1]), 1, float64(wb[3]) / float64(wb[1])}
+		neutral = []uint32{uint32(wb[1]), uint32(wb[0]), 1, 1, uint32(wb[1]), uint32(wb[3])}
+	}
+	gains := [3][3]float64{{balance[0], 0, 0}, {0, balance[1], 0}, {0, 0, balance[2]}}
+	xyzToCamera := invert3(mul3(cameraToXYZ(rw.colorMatrix), gains))
+	matrix := make([]int32, 0, 18)
+	for _, row := range xyzToCamera {
+		for _, v := range row {
+			matrix = append(matrix, int32(math.Round(v*10000)), 10000)
+		}
+	}
+
+	return []entryValues{
+		{CFARepeatPatternDim, SHORT, []uint16{2, 2}},
+		{CFAPattern2, BYTE, pattern[:]},
+		{CFAPlaneColor, BYTE, []byte{cfaRed, cfaGreen, cfaBlue}},
+		{CFALayout, SHORT, []uint16{1}},
+		{BlackLevelRepeatDim, SHORT, []uint16{2, 2}},
+		{DNGBlackLevel, SHORT, black[:]},
+		{DNGWhiteLevel, LONG, []uint32{1<<sensorBits(rw) - 1}},
+		{ColorMatrix1, SRATIONAL, matrix},
+		{AsShotNeutral, RATIONAL, neutral},
+		{CalibrationIlluminant1, SHORT, []uint16{illuminantD65}},
+	}
+}
+
+//uniqueCameraModel joins Make and Model of IFD0.
+func uniqueCameraModel(ifd0 EXIFIFD) string {
+	var parts []string
+	for i, fia := range ifd0.FIA {
+		if (fia.Tag == Make || fia.Tag == Model) && ifd0.FIAvals[i].ascii != nil {
+			if part := strings.TrimRight(string(*ifd0.FIAvals[i].ascii), "\x00 "); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "Sony"
+	}
+	return strings.Join(parts, " ")
+}
+
+//writeDNGPreview copies the JPEG preview of IFD0 in to a preview IFD. Errors reading the preview are returned,
+//previews which are not a JPEG are left out so that the raw data is still converted.
+func writeDNGPreview(tw *tiffWriter, f *File, ifd0 EXIFIFD) (uint32, error) {
+	var offset, length uint32
+	for _, fia := range ifd0.FIA {
+		switch fia.Tag {
+		case JPEGInterchangeFormat:
+			offset = fia.Offset
+		case JPEGInterchangeFormatLength:
+			length = fia.Offset
+		}
+	}
+	if offset == 0 || length == 0 || length > maxValueSize {
+		return 0, nil
+	}
+
+	preview := make([]byte, length)
+	if _, err := f.r.Seek(int64(offset), 0); err != nil {
+		return 0, err
+	}
+	if _, err := io.ReadFull(f.r, preview); err != nil {
+		return 0, errors.New("preview: " + err.Error())
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(preview))
+	if err != nil {
+		return 0, nil
+	}
+
+	previewOffset := tw.write(preview)
+	entries, err := newEntries(tw.order, []entryValues{
+		{NewSubFileType, LONG, []uint32{1}},
+		{ImageWidth, LONG, []uint32{uint32(cfg.Width)}},
+		{ImageHeight, LONG, []uint32{uint32(cfg.Height)}},
+		{BitsPerSample, SHORT, []uint16{8, 8, 8}},
+		{Compression, SHORT, []uint16{compressionJPEG}},
+		{PhotometricInterpretation, SHORT, []uint16{photometricYCbCr}},
+		{StripOffsets, LONG, []uint32{previewOffset}},
+		{SamplesPerPixel, SHORT, []uint16{3}},
+		{RowsPerStrip, LONG, []uint32{uint32(cfg.Height)}},
+		{StripByteCounts, LONG, []uint32{length}},
+		{PlanarConfiguration, SHORT, []uint16{1}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return tw.writeIFD(entries, 0), nil
+}
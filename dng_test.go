@@ -0,0 +1,174 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestWriteDNG(t *testing.T) {
+	const width, height = 16, 8
+	samples := make([]uint16, width*height)
+	for i := range samples {
+		samples[i] = uint16(i * 97 % 0x4000)
+	}
+
+	var dng bytes.Buffer
+	if err := WriteDNG(&dng, bytes.NewReader(buildRaw14ARW(t, width, height, samples)), nil); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	f, err := NewFile(bytes.NewReader(dng.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	tree, err := f.ReadTree()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	values := func(path string) map[IFDtag]FIAval {
+		node := tree.Find(path)
+		if node == nil {
+			t.Fatal(path, "missing")
+		}
+		m := make(map[IFDtag]FIAval)
+		for i, fia := range node.IFD.FIA {
+			m[fia.Tag] = node.IFD.FIAvals[i]
+		}
+		return m
+	}
+
+	ifd0 := values("IFD0")
+	for tag, expected := range map[IFDtag]string{
+		NewSubFileType:            "0",
+		ImageWidth:                "16",
+		ImageHeight:               "8",
+		BitsPerSample:             "16",
+		PhotometricInterpretation: "32803",
+		CFARepeatPatternDim:       "2, 2",
+		CFAPattern2:               "00010102",
+		DNGVersion:                "01040000",
+		DNGBlackLevel:             "512, 513, 514, 515",
+		DNGWhiteLevel:             "16383",
+		Make:                      "SONY\x00",
+		UniqueCameraModel:         "SONY ILCE-7M3\x00",
+	} {
+		if got := ifd0[tag].String(); got != expected {
+			t.Errorf("%v: expected %q, got %q", tag, expected, got)
+		}
+	}
+
+	//The CFA samples are copied unchanged
+	offset := (*ifd0[StripOffsets].long)[0]
+	strip := dng.Bytes()[offset:]
+	for i, v := range samples {
+		if got := binary.LittleEndian.Uint16(strip[i*2:]); got != v {
+			t.Errorf("sample %v: expected %v, got %v", i, v, got)
+			break
+		}
+	}
+
+	//XYZ of the D65 white has to map on to the neutral of the shot
+	neutral := *ifd0[AsShotNeutral].rat
+	matrix := *ifd0[ColorMatrix1].rat
+	white := [3]float64{0.95047, 1, 1.08883}
+	for i := 0; i < 3; i++ {
+		camera := float64(matrix[i*3])*white[0] + float64(matrix[i*3+1])*white[1] + float64(matrix[i*3+2])*white[2]
+		if math.Abs(camera-float64(neutral[i])) > 1e-3 {
+			t.Errorf("channel %v: white maps to %v, neutral is %v", i, camera, neutral[i])
+		}
+	}
+	if math.Abs(float64(neutral[0])-1024.0/2600) > 1e-6 || neutral[1] != 1 {
+		t.Error("Unexpected neutral:", neutral)
+	}
+
+	exif := values("IFD0/Exif")
+	if exif[FNumber].String() != "2.8" || exif[ExposureTime].String() != "0.004" {
+		t.Error("Exif values not copied:", exif[FNumber], exif[ExposureTime])
+	}
+	if _, ok := exif[MakerNote]; ok {
+		t.Error("Expected the makernote to be left out")
+	}
+
+	preview := values("IFD0/SubIFD0")
+	if preview[NewSubFileType].String() != "1" || preview[ImageWidth].String() != "32" {
+		t.Error("Unexpected preview IFD:", preview[NewSubFileType], preview[ImageWidth])
+	}
+	start, length := (*preview[StripOffsets].long)[0], (*preview[StripByteCounts].long)[0]
+	if _, err := jpeg.Decode(bytes.NewReader(dng.Bytes()[start : start+length])); err != nil {
+		t.Error("Preview does not decode:", err)
+	}
+}
+
+func TestWriteDNGTruncatedPreview(t *testing.T) {
+	arw := buildRaw14ARW(t, 16, 8, make([]uint16, 16*8))
+	//Point the preview at the end of the strip so that it runs past the end of the file
+	entry := arw[8+2+4*12:]
+	if IFDtag(binary.LittleEndian.Uint16(entry)) != JPEGInterchangeFormat {
+		t.Fatal("Unexpected layout of IFD0")
+	}
+	binary.LittleEndian.PutUint32(entry[8:], uint32(len(arw)-16))
+	if err := WriteDNG(io.Discard, bytes.NewReader(arw), nil); err == nil {
+		t.Error("Expected the truncated preview to fail")
+	}
+}
+
+func TestWriteDNGCRAW(t *testing.T) {
+	//Every line holds a single 32 pixel group of flat blocks, the samples hit the segments of the curve below
+	const width, height = 32, 4
+	codes := []uint16{1000, 1300, 0, 2047}
+	expected := []uint16{2000, 3200, 0, 0x3fff}
+	strip := make([]byte, width*height)
+	for y, v := range codes {
+		for b := 0; b < 2; b++ {
+			head := uint32(v) | uint32(v)<<11 | 1<<22
+			binary.LittleEndian.PutUint32(strip[y*width+b*pixelBlockSize:], head)
+		}
+	}
+	curve := testEntry{SonyCurve, SHORT, []uint16{8000, 10400, 12900, 14100}}
+	arw := buildARW(t, width, height, craw, 12, [4]uint16{512, 512, 512, 512}, strip, curve)
+
+	var dng bytes.Buffer
+	if err := WriteDNG(&dng, bytes.NewReader(arw), nil); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	f, err := NewFile(bytes.NewReader(dng.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	white, _ := ifd0.Lookup(DNGWhiteLevel)
+	offset, _ := ifd0.Lookup(StripOffsets)
+	if level, err := white.Uint(); err != nil || level != 0x3fff {
+		t.Error("Unexpected white level:", level, err)
+	}
+	start, err := offset.Uint()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	//The samples are linear 14 bit values
+	samples := dng.Bytes()[start:]
+	for y, v := range expected {
+		for x := 0; x < width; x++ {
+			if got := binary.LittleEndian.Uint16(samples[(y*width+x)*2:]); got != v {
+				t.Errorf("sample (%v,%v): expected %v, got %v", x, y, v, got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+//Editor changes the entries of the IFDs of a TIFF document such as ARW.
+//Bytes of the original document never move: values which no longer fit and IFDs which grow are appended to the end
+//and the offsets pointing to them, such as the header, next IFD offsets, SubIFDs, ExifTag or GPSTag, are updated.
+//Offsets to raw strips, previews (JPEGInterchangeFormat) and makernote data therefore remain valid without being touched.
+type Editor struct {
+	buf   []byte
+	order binary.ByteOrder
+	ifds  []*editIFD
+	sony  bool //Make of IFD0 is SONY, DNGPrivateData points to an IFD only then
+}
+
+//editIFD is an IFD as it is currently laid out in the document.
+type editIFD struct {
+	path     string
+	offset   int64
+	capacity int //Number of entries which fit at offset
+	entries  []IFDFIA
+	next     uint32
+
+	//The IFD is referenced by the next IFD offset of parent when tag is zero, or by entry tag of parent otherwise.
+	//IFD0 has no parent and is referenced by the header.
+	parent *editIFD
+	tag    IFDtag
+	index  int
+}
+
+//offsetTags hold offsets in to the document which the editor can not relocate.
+var offsetTags = map[IFDtag]bool{
+	StripOffsets:          true,
+	TileOffsets:           true,
+	JPEGInterchangeFormat: true,
+	SR2SubIFDOffset:       true,
+	MakerNote:             true,
+}
+
+//NewEditor reads the document from r and the IFDs which can be edited, named by path as in ReadTree.
+//Makernotes and SR2 data are not editable.
+func NewEditor(r io.Reader) (*Editor, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > math.MaxUint32 {
+		return nil, errors.New("document too large to edit: " + fmt.Sprint(len(buf)))
+	}
+	f, err := NewFile(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Editor{buf: buf, order: f.order}
+	if ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0); err == nil {
+		e.sony = sonyMake(ifd0)
+	}
+	seen := make(map[int64]bool)
+	var parent *editIFD
+	for offset, i := int64(f.Header.Offset), 0; offset != 0; i++ {
+		if seen[offset] || i == maxIFDChain {
+			return nil, errors.New("IFD chain loops back to offset: " + fmt.Sprint(offset))
+		}
+		ifd, err := e.read(f, seen, "IFD"+fmt.Sprint(i), offset, parent, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		parent = ifd
+		offset = int64(ifd.next)
+	}
+	return e, nil
+}
+
+//read adds the IFD at offset and its descendants.
+func (e *Editor) read(f *File, seen map[int64]bool, path string, offset int64, parent *editIFD, tag IFDtag, index int) (*editIFD, error) {
+	seen[offset] = true
+	meta, err := f.ExtractMetaData(offset, 0)
+	if err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	ifd := &editIFD{path, offset, len(meta.FIA), meta.FIA, meta.Offset, parent, tag, index}
+	e.ifds = append(e.ifds, ifd)
+
+	for i, fia := range meta.FIA {
+		name, ok := childIFDs[fia.Tag]
+		if !ok || fia.Tag == MakerNote || (fia.Tag == DNGPrivateData && !e.sony) {
+			continue
+		}
+		offsets := []uint32{fia.Offset}
+		if long := meta.FIAvals[i].long; long != nil {
+			offsets = *long
+		}
+		for n, child := range offsets {
+			childPath := path + "/" + name
+			if fia.Tag == SubIFDs {
+				childPath += fmt.Sprint(n)
+			}
+			if child == 0 || seen[int64(child)] {
+				continue
+			}
+			if _, err := e.read(f, seen, childPath, int64(child), ifd, fia.Tag, n); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ifd, nil
+}
+
+func (e *Editor) find(path string) (*editIFD, error) {
+	for _, ifd := range e.ifds {
+		if ifd.path == path {
+			return ifd, nil
+		}
+	}
+	return nil, errors.New("no IFD at path: " + path)
+}
+
+//Paths returns the paths of the IFDs which can be edited.
+func (e *Editor) Paths() []string {
+	paths := make([]string, len(e.ifds))
+	for i, ifd := range e.ifds {
+		paths[i] = ifd.path
+	}
+	return paths
+}
+
+//Set replaces the values of tag in the IFD at path, or adds the entry when the IFD does not have it.
+//Values are a string for ASCII, []byte for BYTE and UNDEFINED, []uint16, []int16, []uint32 or []int32 for the
+//integer types and numerator, denominator pairs of []uint32 or []int32 for RATIONAL and SRATIONAL.
+func (e *Editor) Set(path string, tag IFDtag, typ IFDtype, values interface{}) error {
+	if _, ok := childIFDs[tag]; ok || offsetTags[tag] {
+		return errors.New("entries holding offsets can not be set: " + fmt.Sprint(tag))
+	}
+	ifd, err := e.find(path)
+	if err != nil {
+		return err
+	}
+	data, count, err := encodeValues(e.order, typ, values)
+	if err != nil {
+		return errors.New(fmt.Sprint(tag) + ": " + err.Error())
+	}
+
+	i := sort.Search(len(ifd.entries), func(i int) bool { return ifd.entries[i].Tag >= tag })
+	if i == len(ifd.entries) || ifd.entries[i].Tag != tag {
+		ifd.entries = append(ifd.entries, IFDFIA{})
+		copy(ifd.entries[i+1:], ifd.entries[i:])
+		ifd.entries[i] = IFDFIA{Tag: tag}
+	}
+	entry := &ifd.entries[i]
+
+	oldSize := valueSize(*entry)
+	switch {
+	case len(data) <= 4:
+		inline := make([]byte, 4)
+		copy(inline, data)
+		entry.Offset = e.order.Uint32(inline)
+	case int64(len(data)) <= oldSize && oldSize > 4:
+		//Reuse the space of the old values
+		old := e.buf[entry.Offset : int64(entry.Offset)+oldSize]
+		copy(old, data)
+		zero(old[len(data):])
+	default:
+		offset, err := e.append(data)
+		if err != nil {
+			return err
+		}
+		entry.Offset = offset
+	}
+	entry.Type, entry.Count = typ, count
+
+	return e.write(ifd)
+}
+
+//Remove deletes the entry for tag from the IFD at path, IFDs the entry points to are no longer reachable.
+//The values of the entry are left in the document, use RemoveIFD to erase an IFD such as GPS.
+func (e *Editor) Remove(path string, tag IFDtag) error {
+	ifd, err := e.find(path)
+	if err != nil {
+		return err
+	}
+	for i := range ifd.entries {
+		if ifd.entries[i].Tag == tag {
+			ifd.entries = append(ifd.entries[:i], ifd.entries[i+1:]...)
+			e.forget(func(child *editIFD) bool { return child.parent == ifd && child.tag == tag })
+			return e.write(ifd)
+		}
+	}
+	return errors.New(path + " has no entry " + fmt.Sprint(tag))
+}
+
+//RemoveIFD erases the IFD at path, its descendants and their values and unlinks it from the document.
+//Data referenced through offset entries, such as strips or previews, is left in place.
+func (e *Editor) RemoveIFD(path string) error {
+	ifd, err := e.find(path)
+	if err != nil {
+		return err
+	}
+	if ifd.parent == nil {
+		return errors.New("IFD0 can not be removed")
+	}
+
+	e.erase(ifd)
+	switch {
+	case ifd.tag == 0:
+		ifd.parent.next = ifd.next
+		err = e.write(ifd.parent)
+		//The IFD after the removed one is now referenced by the parent
+		for _, other := range e.ifds {
+			if other.parent == ifd && other.tag == 0 {
+				other.parent = ifd.parent
+			}
+		}
+	case valueSize(*e.entry(ifd.parent, ifd.tag)) > 4:
+		//One of several SubIFDs, its offset is cleared in the array
+		err = e.point(ifd, 0)
+	default:
+		err = e.Remove(ifd.parent.path, ifd.tag)
+	}
+	e.forget(func(other *editIFD) bool { return other == ifd })
+	return err
+}
+
+//erase zeroes an IFD, its values and its descendants.
+func (e *Editor) erase(ifd *editIFD) {
+	for _, child := range e.ifds {
+		if child.parent == ifd && child.tag != 0 {
+			e.erase(child)
+		}
+	}
+	for _, entry := range ifd.entries {
+		if size := valueSize(entry); size > 4 {
+			zero(e.buf[entry.Offset : int64(entry.Offset)+size])
+		}
+	}
+	zero(e.buf[ifd.offset : ifd.offset+ifdSize(ifd.capacity)])
+}
+
+//forget drops the IFDs matching fn and their descendants from the editable IFDs.
+func (e *Editor) forget(fn func(ifd *editIFD) bool) {
+	var dropped []*editIFD
+	kept := e.ifds[:0]
+	for _, ifd := range e.ifds {
+		drop := fn(ifd)
+		for _, d := range dropped {
+			drop = drop || (ifd.parent == d && ifd.tag != 0)
+		}
+		if drop {
+			dropped = append(dropped, ifd)
+		} else {
+			kept = append(kept, ifd)
+		}
+	}
+	e.ifds = kept
+}
+
+//write lays out the IFD in its current space, or appends it when it has grown and updates the offset pointing to it.
+func (e *Editor) write(ifd *editIFD) error {
+	if len(ifd.entries) > maxIFDEntries {
+		return errors.New("too many IFD entries: " + fmt.Sprint(len(ifd.entries)))
+	}
+	var out bytes.Buffer
+	binary.Write(&out, e.order, uint16(len(ifd.entries)))
+	binary.Write(&out, e.order, ifd.entries)
+	binary.Write(&out, e.order, ifd.next)
+
+	if len(ifd.entries) <= ifd.capacity {
+		space := e.buf[ifd.offset : ifd.offset+ifdSize(ifd.capacity)]
+		copy(space, out.Bytes())
+		zero(space[out.Len():])
+		return nil
+	}
+
+	offset, err := e.append(out.Bytes())
+	if err != nil {
+		return err
+	}
+	zero(e.buf[ifd.offset : ifd.offset+ifdSize(ifd.capacity)])
+	ifd.offset, ifd.capacity = int64(offset), len(ifd.entries)
+	return e.point(ifd, offset)
+}
+
+//point updates the offset referencing ifd.
+func (e *Editor) point(ifd *editIFD, offset uint32) error {
+	switch {
+	case ifd.parent == nil:
+		e.order.PutUint32(e.buf[4:], offset)
+		return nil
+	case ifd.tag == 0:
+		ifd.parent.next = offset
+		return e.write(ifd.parent)
+	}
+
+	entry := e.entry(ifd.parent, ifd.tag)
+	if valueSize(*entry) <= 4 {
+		entry.Offset = offset
+		return e.write(ifd.parent)
+	}
+	//Several SubIFDs are stored as an array of offsets
+	e.order.PutUint32(e.buf[int64(entry.Offset)+4*int64(ifd.index):], offset)
+	return nil
+}
+
+func (e *Editor) entry(ifd *editIFD, tag IFDtag) *IFDFIA {
+	for i := range ifd.entries {
+		if ifd.entries[i].Tag == tag {
+			return &ifd.entries[i]
+		}
+	}
+	return &IFDFIA{}
+}
+
+//append adds data to the end of the document at a word boundary and returns its offset.
+func (e *Editor) append(data []byte) (uint32, error) {
+	if len(e.buf)%2 == 1 {
+		e.buf = append(e.buf, 0)
+	}
+	if int64(len(e.buf))+int64(len(data)) > math.MaxUint32 {
+		return 0, errors.New("edited document exceeds 4GB")
+	}
+	offset := uint32(len(e.buf))
+	e.buf = append(e.buf, data...)
+	return offset, nil
+}
+
+//Bytes returns the edited document.
+func (e *Editor) Bytes() []byte {
+	return e.buf
+}
+
+//WriteTo writes the edited document to w.
+func (e *Editor) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(e.buf)
+	return int64(n), err
+}
+
+//valueSize is the size of the values of an entry, values of 4 bytes or less are stored in the entry itself.
+func valueSize(entry IFDFIA) int64 {
+	return int64(entry.Type.Len()) * int64(entry.Count)
+}
+
+func ifdSize(entries int) int64 {
+	return 2 + 12*int64(entries) + 4
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+//encodeValues returns values in the byte order of the document together with the count of the entry.
+func encodeValues(order binary.ByteOrder, typ IFDtype, values interface{}) ([]byte, uint32, error) {
+	ok := false
+	switch v := values.(type) {
+	case string:
+		ok = typ == ASCII
+		values = append([]byte(v), 0)
+	case []byte:
+		ok = typ == BYTE || typ == ASCII || typ == UNDEFINED
+	case []uint16:
+		ok = typ == SHORT
+	case []int16:
+		ok = typ == SSHORT
+	case []uint32:
+		ok = typ == LONG || (typ == RATIONAL && len(v)%2 == 0)
+	case []int32:
+		ok = typ == SLONG || (typ == SRATIONAL && len(v)%2 == 0)
+	}
+	if !ok {
+		return nil, 0, errors.New("values of type " + fmt.Sprintf("%T", values) + " can not be stored as " + fmt.Sprint(typ))
+	}
+
+	var out bytes.Buffer
+	if err := binary.Write(&out, order, values); err != nil {
+		return nil, 0, err
+	}
+	if out.Len() > maxValueSize {
+		return nil, 0, errors.New("values are too large: " + fmt.Sprint(out.Len()))
+	}
+	return out.Bytes(), uint32(out.Len() / typ.Len()), nil
+}
@@ -0,0 +1,140 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		orig := buildEditTIFF(order)
+		e, err := NewEditor(bytes.NewReader(orig))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+
+		edits := []error{
+			e.Set("IFD0/Exif", DateTimeOriginal, ASCII, "2022:03:14 15:09:26"),
+			e.Set("IFD0", Model, ASCII, "ILCE-1"),
+			e.Set("IFD0", Make, ASCII, "SONY CORPORATION"),
+			e.Set("IFD0", Copyright, ASCII, "Jane Doe"),
+			e.Set("IFD0", Artist, ASCII, "Jane Doe"),
+			e.Set("IFD0/Exif", FNumber, RATIONAL, []uint32{28, 10}),
+			e.Set("IFD0/SubIFD0", BitsPerSample, SHORT, []uint16{14}),
+			e.RemoveIFD("IFD0/GPS"),
+		}
+		for _, err := range edits {
+			if err != nil {
+				t.Error(order, err)
+			}
+		}
+		if err := e.Set("IFD0", JPEGInterchangeFormat, LONG, []uint32{0}); err == nil {
+			t.Error("Expected an error setting an offset")
+		}
+		if err := e.Set("IFD0", Make, SHORT, "SONY"); err == nil {
+			t.Error("Expected an error for mismatched values")
+		}
+
+		edited := e.Bytes()
+		if !bytes.Equal(edited[900:1064], orig[900:1064]) {
+			t.Error(order, "preview or strip data moved")
+		}
+		if bytes.Contains(edited, orig[500:500+2+2*12+4]) {
+			t.Error(order, "GPS IFD left in the document")
+		}
+
+		tree, err := ReadTree(bytes.NewReader(edited))
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+		check := func(path string, tag IFDtag, expected string) {
+			node := tree.Find(path)
+			if node == nil {
+				t.Errorf("%v: %v missing", order, path)
+				return
+			}
+			for i, fia := range node.IFD.FIA {
+				if fia.Tag == tag {
+					if got := node.IFD.FIAvals[i].String(); got != expected {
+						t.Errorf("%v: %v %v expected %q, got %q", order, path, tag, expected, got)
+					}
+					return
+				}
+			}
+			t.Errorf("%v: %v has no %v", order, path, tag)
+		}
+		check("IFD0", Make, "SONY CORPORATION\x00")
+		check("IFD0", Model, "ILCE-1\x00")
+		check("IFD0", Copyright, "Jane Doe\x00")
+		check("IFD0", Artist, "Jane Doe\x00")
+		check("IFD0", JPEGInterchangeFormat, "900")
+		check("IFD0/Exif", DateTimeOriginal, "2022:03:14 15:09:26\x00")
+		check("IFD0/Exif", ExposureTime, "0.004")
+		check("IFD0/Exif", FNumber, "2.8")
+		check("IFD0/SubIFD0", StripOffsets, "1000")
+		check("IFD0/SubIFD0", BitsPerSample, "14")
+		check("IFD1", ImageWidth, "160")
+		if tree.Find("IFD0/GPS") != nil {
+			t.Error(order, "GPS IFD still linked")
+		}
+
+		//IFD entries must stay sorted by tag
+		tree.Walk(func(n *IFDNode) error {
+			for i := 1; i < len(n.IFD.FIA); i++ {
+				if n.IFD.FIA[i-1].Tag >= n.IFD.FIA[i].Tag {
+					t.Errorf("%v: %v entries out of order", order, n.Path)
+					break
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func TestEditorRemove(t *testing.T) {
+	e, err := NewEditor(bytes.NewReader(buildEditTIFF(binary.LittleEndian)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := e.Remove("IFD0", Model); err != nil {
+		t.Error(err)
+	}
+	if err := e.RemoveIFD("IFD1"); err != nil {
+		t.Error(err)
+	}
+	if err := e.Remove("IFD0", Model); err == nil {
+		t.Error("Expected an error removing a missing entry")
+	}
+
+	f, err := NewFile(bytes.NewReader(e.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	ifds, err := f.IFDs()
+	if err != nil || len(ifds) != 1 {
+		t.Error("Expected only IFD0 to be left:", len(ifds), err)
+		t.FailNow()
+	}
+	for _, fia := range ifds[0].FIA {
+		if fia.Tag == Model {
+			t.Error("Model was not removed")
+		}
+	}
+}
+
+func TestEditorForeignDNGPrivateData(t *testing.T) {
+	doc := buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{Make, ASCII, []byte("Canon\x00")}, {DNGPrivateData, BYTE, []byte("Adobe\x00MakN\x00\x00\x00\x08")}}})
+	e, err := NewEditor(bytes.NewReader(doc))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if paths := e.Paths(); len(paths) != 1 || paths[0] != "IFD0" {
+		t.Error("Expected only IFD0, got:", paths)
+	}
+}
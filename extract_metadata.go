@@ -1,9 +1,11 @@
 package arw
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"image"
 	"io"
-	"log"
 )
 
 type rawDetails struct {
@@ -14,31 +16,55 @@ type rawDetails struct {
 	offset        uint32
 	stride        uint32
 	length        uint32
+	tileWidth     uint32
+	tileHeight    uint32
+	tileOffsets   []uint32
+	tileLengths   []uint32
 	blackLevel    [4]uint16
 	WhiteBalance  [4]int16
+	daylight      [4]int16 //RGGB, zero when the SR2SubIFD lacks the preset
 	gammaCurve    [5]uint16
 	crop          image.Rectangle
-	cfaPattern    [4]uint8 //TODO(sjon): This might not always be 4 bytes is my suspicion. We currently take from the offset
+	cfaPattern    [4]uint8 //TODO(sjon): This might not always be 4 bytes is my suspicion, only the first 4 are kept
 	cfaPatternDim [2]uint16
+	colorMatrix   [9]int16
 	aperture      float32
 	shutter       float32
 	iso           uint16
 	focalLength   float32
 	lensModel     string
+	order         binary.ByteOrder
+}
+
+//byteOrder returns the byte order of the file the details were read from, ARW files are little endian.
+func (rw rawDetails) byteOrder() binary.ByteOrder {
+	if rw.order == nil {
+		return binary.LittleEndian
+	}
+	return rw.order
 }
 
 func extractDetails(rs io.ReadSeeker) (rawDetails, error) {
 	var rw rawDetails
 
-	header, err := ParseHeader(rs)
-	meta, err := ExtractMetaData(rs, int64(header.Offset), 0)
+	f, err := NewFile(rs)
 	if err != nil {
 		return rw, err
 	}
+	rw.order = f.ByteOrder()
+	meta, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return rw, err
+	}
+
+	//The SR2SubIFD repeats the black level and white balance, these are used when the raw IFD lacks them
+	var haveBlack, haveBalance bool
+	var sr2Black *[]uint16
+	var sr2Balance *[]int16
 
 	for _, fia := range meta.FIA {
 		if fia.Tag == SubIFDs {
-			rawIFD, err := ExtractMetaData(rs, int64(fia.Offset), 0)
+			rawIFD, err := f.ExtractMetaData(int64(fia.Offset), 0)
 			if err != nil {
 				return rw, err
 			}
@@ -46,112 +72,200 @@ func extractDetails(rs io.ReadSeeker) (rawDetails, error) {
 			for i, v := range rawIFD.FIA {
 				switch v.Tag {
 				case ImageWidth:
-					rw.width = uint16(v.Offset)
+					rw.width = uint16(entryUint(v, rawIFD.FIAvals[i]))
 				case ImageHeight:
-					rw.height = uint16(v.Offset)
+					rw.height = uint16(entryUint(v, rawIFD.FIAvals[i]))
 				case BitsPerSample:
-					rw.bitDepth = uint16(v.Offset)
+					rw.bitDepth = uint16(entryUint(v, rawIFD.FIAvals[i]))
 				case SonyRawFileType:
-					rw.rawType = sonyRawFile(v.Offset)
+					rw.rawType = sonyRawFile(entryUint(v, rawIFD.FIAvals[i]))
 				case StripOffsets:
-					rw.offset = v.Offset
+					rw.offset = entryUint(v, rawIFD.FIAvals[i])
 				case RowsPerStrip:
-					rw.stride = v.Offset //TODO(sjon): Uncompressed RAW files are 2 bytes per pixel whereas CRAW is 1 byte per pixel, this shouldn't be set here! current behaviour is for CRAW, add a divide by 2 for RAW
+					rw.stride = entryUint(v, rawIFD.FIAvals[i]) //TODO(sjon): Uncompressed RAW files are 2 bytes per pixel whereas CRAW is 1 byte per pixel, this shouldn't be set here! current behaviour is for CRAW, add a divide by 2 for RAW
 				case StripByteCounts:
-					rw.length = v.Offset
+					rw.length = entryUint(v, rawIFD.FIAvals[i])
+				case TileWidth:
+					rw.tileWidth = entryUint(v, rawIFD.FIAvals[i])
+				case TileLength:
+					rw.tileHeight = entryUint(v, rawIFD.FIAvals[i])
+				case TileOffsets:
+					if offsets := rawIFD.FIAvals[i].long; offsets != nil {
+						rw.tileOffsets = *offsets
+					}
+				case TileByteCounts:
+					if lengths := rawIFD.FIAvals[i].long; lengths != nil {
+						rw.tileLengths = *lengths
+					}
 				case SonyCurve:
-					curve := *rawIFD.FIAvals[i].short
-					copy(rw.gammaCurve[:4], curve)
-					rw.gammaCurve[4] = 0x3fff
+					if curve := rawIFD.FIAvals[i].short; curve != nil {
+						copy(rw.gammaCurve[:4], *curve)
+						rw.gammaCurve[4] = 0x3fff
+					}
 				case BlackLevel2:
-					black := *rawIFD.FIAvals[i].short
-					copy(rw.blackLevel[:], black)
+					if black := rawIFD.FIAvals[i].short; black != nil {
+						copy(rw.blackLevel[:], *black)
+						haveBlack = true
+					}
 				case WB_RGGBLevels:
-					balance := *rawIFD.FIAvals[i].sshort
-					copy(rw.WhiteBalance[:], balance)
+					if balance := rawIFD.FIAvals[i].sshort; balance != nil {
+						copy(rw.WhiteBalance[:], *balance)
+						haveBalance = true
+					}
 				case DefaultCropSize:
 				case CFAPattern2:
-					rw.cfaPattern[0] = uint8((v.Offset & 0x000000ff) >> 0)
-					rw.cfaPattern[1] = uint8((v.Offset & 0x0000ff00) >> 8)
-					rw.cfaPattern[2] = uint8((v.Offset & 0x00ff0000) >> 16)
-					rw.cfaPattern[3] = uint8((v.Offset & 0xff000000) >> 24)
+					if pattern := rawIFD.FIAvals[i].ascii; pattern != nil {
+						copy(rw.cfaPattern[:], *pattern)
+					}
 				case CFARepeatPatternDim:
-					rw.cfaPatternDim[0] = uint16((v.Offset & 0x0000ffff) >> 0)
-					rw.cfaPatternDim[1] = uint16((v.Offset & 0xffff0000) >> 16)
+					if dim := rawIFD.FIAvals[i].short; dim != nil {
+						copy(rw.cfaPatternDim[:], *dim)
+					}
 				}
 			}
 		}
 
 		if fia.Tag == ExifTag {
-			exif, err := ExtractMetaData(rs, int64(fia.Offset), 0)
+			exif, err := f.ExtractMetaData(int64(fia.Offset), 0)
 			if err != nil {
 				return rw, err
 			}
 			for i, v := range exif.FIA {
 				switch v.Tag {
 				case ExposureTime:
-					rw.shutter = (*exif.FIAvals[i].rat)[0]
+					if rat := exif.FIAvals[i].rat; rat != nil && len(*rat) > 0 {
+						rw.shutter = (*rat)[0]
+					}
 				case FNumber:
-					rw.aperture = (*exif.FIAvals[i].rat)[0]
+					if rat := exif.FIAvals[i].rat; rat != nil && len(*rat) > 0 {
+						rw.aperture = (*rat)[0]
+					}
 				case ISOSpeedRatings:
-					rw.iso = uint16((v.Offset & 0x0000ffff) >> 0)
+					rw.iso = uint16(entryUint(v, exif.FIAvals[i]))
 				case FocalLength:
-					rw.focalLength = (*exif.FIAvals[i].rat)[0]
+					if rat := exif.FIAvals[i].rat; rat != nil && len(*rat) > 0 {
+						rw.focalLength = (*rat)[0]
+					}
 				case LensModel:
-					rw.lensModel = string(*exif.FIAvals[i].ascii)
+					if model := exif.FIAvals[i].ascii; model != nil {
+						rw.lensModel = string(*model)
+					}
 				}
 			}
 
 		}
 
-		//if fia.Tag == DNGPrivateData {
-		//	dng, err := ExtractMetaData(rs, int64(fia.Offset), 0)
-		//	if err != nil {
-		//		return rw, err
-		//	}
-		//
-		//	var sr2offset uint32
-		//	var sr2length uint32
-		//	var sr2key [4]byte
-		//
-		//	for i := range dng.FIA {
-		//		if dng.FIA[i].Tag == SR2SubIFDOffset {
-		//			offset := dng.FIA[i].Offset
-		//			sr2offset = offset
-		//		}
-		//		if dng.FIA[i].Tag == SR2SubIFDLength {
-		//			sr2length = dng.FIA[i].Offset
-		//		}
-		//		if dng.FIA[i].Tag == SR2SubIFDKey {
-		//			key := dng.FIA[i].Offset*0x0edd + 1
-		//			sr2key[3] = byte((key >> 24) & 0xff)
-		//			sr2key[2] = byte((key >> 16) & 0xff)
-		//			sr2key[1] = byte((key >> 8) & 0xff)
-		//			sr2key[0] = byte((key) & 0xff)
-		//		}
-		//	}
-		//
-		//	buf := DecryptSR2(rs, sr2offset, sr2length)
-		//	br := bytes.NewReader(buf)
-		//
-		//	sr2, err := ExtractMetaData(br, 0, 0)
-		//	if err != nil {
-		//		log.Fatal(err)
-		//	}
-		//
-		//	for i, v := range sr2.FIA {
-		//		switch v.Tag {
-		//		case BlackLevel2:
-		//			black := *sr2.FIAvals[i].short
-		//			copy(rw.blackLevel[:], black)
-		//		case WB_RGGBLevels:
-		//			balance := *sr2.FIAvals[i].sshort
-		//			copy(rw.WhiteBalance[:], balance)
-		//		}
-		//	}
-		//}
-	}
-
-	log.Printf("%+v\n", rw)
+		if fia.Tag == DNGPrivateData {
+			//The SR2SubIFD only adds to the raw IFD, a damaged one leaves the decode to the values found there
+			sr2, err := readSR2(f, int64(fia.Offset))
+			if err != nil {
+				continue
+			}
+
+			for i, v := range sr2.FIA {
+				switch v.Tag {
+				case ColorMatrix:
+					if matrix := sr2.FIAvals[i].sshort; matrix != nil {
+						copy(rw.colorMatrix[:], *matrix)
+					}
+				case BlackLevel2:
+					sr2Black = sr2.FIAvals[i].short
+				case WB_RGGBLevels:
+					sr2Balance = sr2.FIAvals[i].sshort
+				case WB_RGBLevelsDaylight:
+					//Stored as RGB by some models and RGGB by others
+					if levels := sr2.FIAvals[i].sshort; levels != nil && len(*levels) == 3 {
+						rw.daylight = [4]int16{(*levels)[0], (*levels)[1], (*levels)[1], (*levels)[2]}
+					} else if levels != nil && len(*levels) >= 4 {
+						copy(rw.daylight[:], *levels)
+					}
+				}
+			}
+		}
+	}
+
+	if !haveBlack && sr2Black != nil {
+		copy(rw.blackLevel[:], *sr2Black)
+	}
+	if !haveBalance && sr2Balance != nil {
+		copy(rw.WhiteBalance[:], *sr2Balance)
+	}
+
+	if err := checkCFA(rw.cfaPattern, rw.cfaPatternDim); err != nil {
+		return rw, err
+	}
+
 	return rw, nil
 }
+
+//entryUint returns the first value of a BYTE, SHORT or LONG entry. The offset field only holds a SHORT in the
+//low bits for little endian files, so it is used only for entries of other types.
+func entryUint(fia IFDFIA, val FIAval) uint32 {
+	u, err := Value{Tag: fia.Tag, Type: fia.Type, Count: fia.Count, val: val}.Uint()
+	if err != nil {
+		return fia.Offset
+	}
+	return uint32(u)
+}
+
+//readSR2 decrypts and parses the SR2SubIFD referenced by the DNGPrivateData IFD at offset.
+//Files without SR2 data result in an empty IFD.
+func readSR2(f *File, offset int64) (EXIFIFD, error) {
+	dng, err := f.ExtractMetaData(offset, 0)
+	if err != nil {
+		return EXIFIFD{}, err
+	}
+
+	sr2offset, sr2length, sr2key := sr2Location(dng)
+	if sr2offset == 0 || sr2length == 0 {
+		return EXIFIFD{}, nil
+	}
+	return decryptSR2IFD(f, sr2offset, sr2length, sr2key)
+}
+
+//sr2Location returns the position and key of the encrypted SR2SubIFD from the entries of a DNGPrivateData IFD.
+func sr2Location(dng EXIFIFD) (offset, length, key uint32) {
+	for i := range dng.FIA {
+		switch dng.FIA[i].Tag {
+		case SR2SubIFDOffset:
+			offset = dng.FIA[i].Offset
+		case SR2SubIFDLength:
+			length = dng.FIA[i].Offset
+		case SR2SubIFDKey:
+			key = dng.FIA[i].Offset
+		}
+	}
+	return offset, length, key
+}
+
+//decryptSR2IFD decrypts the SR2SubIFD and parses it in place, its values are referenced by file offset.
+func decryptSR2IFD(f *File, offset, length, key uint32) (EXIFIFD, error) {
+	buf, err := DecryptSR2Key(f.r, offset, length, key)
+	if err != nil {
+		return EXIFIFD{}, err
+	}
+	sr2 := io.NewSectionReader(sr2Reader{buf, int64(offset)}, 0, int64(offset)+int64(len(buf)))
+	return extractMetaData(sr2, f.order, int64(offset), 0)
+}
+
+//checkCFA verifies the CFA layout is a 2x2 pattern with all three colours, a missing layout is taken as RGGB.
+func checkCFA(pattern [4]uint8, dim [2]uint16) error {
+	if dim == [2]uint16{} {
+		return nil
+	}
+	if dim != [2]uint16{2, 2} {
+		return errors.New("unsupported CFA repeat pattern: " + fmt.Sprint(dim))
+	}
+
+	var seen [3]int
+	for _, c := range pattern {
+		if c > cfaBlue {
+			return errors.New("unsupported colour in CFA pattern: " + fmt.Sprint(pattern))
+		}
+		seen[c]++
+	}
+	if seen[cfaRed] == 0 || seen[cfaGreen] == 0 || seen[cfaBlue] == 0 {
+		return errors.New("CFA pattern is missing a colour: " + fmt.Sprint(pattern))
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+//testEntry is a single IFD entry of a test document, values is a slice of the Go type matching typ.
+type testEntry struct {
+	tag    IFDtag
+	typ    IFDtype
+	values interface{}
+}
+
+//testBlock is an IFD with its next IFD offset, or raw data when data is set, placed at offset of a test document.
+type testBlock struct {
+	offset  int
+	entries []testEntry
+	next    uint32
+	data    []byte
+}
+
+//buildTIFF lays out a TIFF document in the given byte order with IFD0 as the first block, right after the header when its offset is 0.
+//Blocks are written in the order given and padded with zeroes up to their offset, values which do not fit in the offset field follow their IFD.
+func buildTIFF(order binary.ByteOrder, blocks ...testBlock) []byte {
+	if blocks[0].offset == 0 {
+		blocks[0].offset = 8
+	}
+	doc := tiffHeader(order, uint32(blocks[0].offset))
+	for _, b := range blocks {
+		if b.data != nil {
+			doc = append(doc, make([]byte, b.offset-len(doc))...)
+			doc = append(doc, b.data...)
+			continue
+		}
+		doc = putIFD(doc, order, b.offset, b.entries, b.next)
+	}
+	return doc
+}
+
+func tiffHeader(order binary.ByteOrder, offset uint32) []byte {
+	var out bytes.Buffer
+	if order == binary.BigEndian {
+		out.WriteString("MM")
+	} else {
+		out.WriteString("II")
+	}
+	binary.Write(&out, order, uint16(42))
+	binary.Write(&out, order, offset)
+	return out.Bytes()
+}
+
+//putIFD appends an IFD at offset to the document, padding it with zeroes as needed, values which do not fit in the offset field follow the IFD.
+func putIFD(doc []byte, order binary.ByteOrder, offset int, entries []testEntry, next uint32) []byte {
+	out := bytes.NewBuffer(doc)
+	out.Write(make([]byte, offset-len(doc)))
+
+	var data bytes.Buffer
+	dataOffset := offset + 2 + len(entries)*12 + 4
+	binary.Write(out, order, uint16(len(entries)))
+	for _, e := range entries {
+		var value bytes.Buffer
+		binary.Write(&value, order, e.values)
+		count := value.Len() / e.typ.Len()
+
+		binary.Write(out, order, uint16(e.tag))
+		binary.Write(out, order, uint16(e.typ))
+		binary.Write(out, order, uint32(count))
+		if value.Len() <= 4 {
+			field := make([]byte, 4)
+			copy(field, value.Bytes())
+			out.Write(field)
+		} else {
+			binary.Write(out, order, uint32(dataOffset+data.Len()))
+			data.Write(value.Bytes())
+		}
+	}
+	binary.Write(out, order, next)
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+//buildTreeTIFF lays out IFD0 with two SubIFDs, an Exif IFD with an Interop IFD, a GPS pointer back to IFD0 and IFD1 at next.
+func buildTreeTIFF(order binary.ByteOrder, next uint32) []byte {
+	return buildTIFF(order,
+		testBlock{entries: []testEntry{
+			{ImageWidth, SHORT, []uint16{6000}},
+			{SubIFDs, LONG, []uint32{300, 400}},
+			{ExifTag, LONG, []uint32{500}},
+			{GPSTag, LONG, []uint32{8}},
+		}, next: 700},
+		testBlock{offset: 300, entries: []testEntry{{BitsPerSample, SHORT, []uint16{14}}}},
+		testBlock{offset: 400, entries: []testEntry{{ImageWidth, SHORT, []uint16{1616}}}},
+		testBlock{offset: 500, entries: []testEntry{
+			{FNumber, RATIONAL, []uint32{28, 10}},
+			{InteroperabilityTag, LONG, []uint32{600}},
+		}},
+		testBlock{offset: 600, entries: []testEntry{{Make, ASCII, []byte("R98\x00")}}},
+		testBlock{offset: 700, entries: []testEntry{{ImageWidth, SHORT, []uint16{160}}}, next: next},
+	)
+}
+
+//buildEditTIFF lays out IFD0 with a raw SubIFD, Exif and GPS IFDs, a preview and a strip, and IFD1 at 700.
+func buildEditTIFF(order binary.ByteOrder) []byte {
+	strip := make([]byte, 64)
+	for i := range strip {
+		strip[i] = byte(i)
+	}
+	return buildTIFF(order,
+		testBlock{entries: []testEntry{
+			{Make, ASCII, []byte("SONY\x00")},
+			{Model, ASCII, []byte("ILCE-7M4\x00")},
+			{SubIFDs, LONG, []uint32{300}},
+			{JPEGInterchangeFormat, LONG, []uint32{900}},
+			{JPEGInterchangeFormatLength, LONG, []uint32{16}},
+			{ExifTag, LONG, []uint32{400}},
+			{GPSTag, LONG, []uint32{500}},
+		}, next: 700},
+		testBlock{offset: 300, entries: []testEntry{
+			{ImageWidth, SHORT, []uint16{16}},
+			{StripOffsets, LONG, []uint32{1000}},
+			{StripByteCounts, LONG, []uint32{64}},
+		}},
+		testBlock{offset: 400, entries: []testEntry{
+			{ExposureTime, RATIONAL, []uint32{1, 250}},
+			{DateTimeOriginal, ASCII, []byte("2021:01:01 00:00:00\x00")},
+		}},
+		testBlock{offset: 500, entries: []testEntry{
+			{GPSLatitudeRef, ASCII, []byte("N\x00")},
+			{GPSLatitude, RATIONAL, []uint32{52, 1, 22, 1, 1234, 100}},
+		}},
+		testBlock{offset: 700, entries: []testEntry{{ImageWidth, SHORT, []uint16{160}}}},
+		testBlock{offset: 900, data: []byte{0xff, 0xd8, 0xff, 0xdb, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 0xff, 0xd9}},
+		testBlock{offset: 1000, data: strip},
+	)
+}
+
+//buildGPSTIFF lays out IFD0 pointing to a GPS IFD with the entries at 100.
+func buildGPSTIFF(order binary.ByteOrder, entries []testEntry) []byte {
+	return buildTIFF(order,
+		testBlock{entries: []testEntry{{GPSTag, LONG, []uint32{100}}}},
+		testBlock{offset: 100, entries: entries},
+	)
+}
+
+//buildSR2TIFF lays out IFD0 with a raw SubIFD and a DNGPrivateData IFD pointing to an encrypted SR2SubIFD at 600.
+func buildSR2TIFF(order binary.ByteOrder, raw, sr2 []testEntry) []byte {
+	const sr2At, key = 600, 0x12345678
+	sr2Length := len(putIFD(nil, order, sr2At, sr2, 0)) - sr2At
+	doc := buildTIFF(order,
+		testBlock{entries: []testEntry{
+			{Make, ASCII, []byte("SONY\x00")},
+			{SubIFDs, LONG, []uint32{300}},
+			{DNGPrivateData, LONG, []uint32{500}},
+		}},
+		testBlock{offset: 300, entries: raw},
+		testBlock{offset: 500, entries: []testEntry{
+			{SR2SubIFDOffset, LONG, []uint32{sr2At}},
+			{SR2SubIFDLength, LONG, []uint32{uint32(sr2Length)}},
+			{SR2SubIFDKey, LONG, []uint32{key}},
+		}},
+		testBlock{offset: sr2At, entries: sr2},
+	)
+	decryptSR2(doc[sr2At:], key)
+	return doc
+}
+
+//buildMakerNoteTIFF places a makernote at offset 300 followed by the Exif IFD, its values relative to the file or to the makernote itself.
+func buildMakerNoteTIFF(order binary.ByteOrder, header string, relative bool, entries []testEntry) []byte {
+	const at = 300
+	var note []byte
+	if relative {
+		note = putIFD([]byte(header), order, len(header), entries, 0)
+	} else {
+		note = putIFD(make([]byte, at+len(header)), order, at+len(header), entries, 0)[at:]
+		copy(note, header)
+	}
+
+	exifAt := (at + len(note) + 1) &^ 1
+	doc := buildTIFF(order,
+		testBlock{entries: []testEntry{{ExifTag, LONG, []uint32{uint32(exifAt)}}}},
+		testBlock{offset: at, data: note},
+		testBlock{offset: exifAt, entries: []testEntry{{MakerNote, UNDEFINED, make([]byte, len(note))}}},
+	)
+	//Point the makernote entry at the copy at 300
+	order.PutUint32(doc[exifAt+2+8:], at)
+	return doc
+}
+
+//buildPreviewTIFF lays out a preview in IFD0, a thumbnail in IFD1 and a makernote PreviewImage using offsets relative to the makernote.
+func buildPreviewTIFF(t *testing.T, orientation uint16) []byte {
+	preview, thumbnail, makernote := testJPEG(t, 64, 32), testJPEG(t, 16, 8), testJPEG(t, 32, 16)
+	order := binary.LittleEndian
+	const exifAt, ifd1At, previewAt = 100, 2000, 2100
+	thumbnailAt := previewAt + len(preview)
+
+	return buildTIFF(order,
+		testBlock{entries: []testEntry{
+			{Orientation, SHORT, []uint16{orientation}},
+			{JPEGInterchangeFormat, LONG, []uint32{previewAt}},
+			{JPEGInterchangeFormatLength, LONG, []uint32{uint32(len(preview))}},
+			{ExifTag, LONG, []uint32{exifAt}},
+		}, next: ifd1At},
+		testBlock{offset: exifAt, entries: []testEntry{
+			{MakerNote, UNDEFINED, putIFD(nil, order, 0, []testEntry{{PreviewImage, UNDEFINED, makernote}}, 0)},
+		}},
+		testBlock{offset: ifd1At, entries: []testEntry{
+			{JPEGInterchangeFormat, LONG, []uint32{uint32(thumbnailAt)}},
+			{JPEGInterchangeFormatLength, LONG, []uint32{uint32(len(thumbnail))}},
+		}},
+		testBlock{offset: previewAt, data: preview},
+		testBlock{offset: thumbnailAt, data: thumbnail},
+	)
+}
+
+//buildRaw14ARW lays out a little endian raw14 ARW of width by height samples with an Exif IFD, an XMP packet and a JPEG preview.
+func buildRaw14ARW(t testing.TB, width, height int, samples []uint16) []byte {
+	strip := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(strip[i*2:], v)
+	}
+	return buildARW(t, width, height, raw14, 14, [4]uint16{512, 513, 514, 515}, strip)
+}
+
+//buildARW lays out a little endian ARW of the raw type with the given strip and RGGB black levels, extra entries are added to the raw IFD.
+func buildARW(t testing.TB, width, height int, rawType sonyRawFile, bits uint16, black [4]uint16, strip []byte, extra ...testEntry) []byte {
+	var preview bytes.Buffer
+	if err := jpeg.Encode(&preview, image.NewGray(image.Rect(0, 0, 32, 24)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const rawAt, exifAt, previewAt, stripAt = 1200, 1400, 1600, 4096
+	return buildTIFF(binary.LittleEndian,
+		testBlock{entries: []testEntry{
+			{Make, ASCII, []byte("SONY\x00")},
+			{Model, ASCII, []byte("ILCE-7M3\x00")},
+			{Orientation, SHORT, []uint16{1}},
+			{SubIFDs, LONG, []uint32{rawAt}},
+			{JPEGInterchangeFormat, LONG, []uint32{previewAt}},
+			{JPEGInterchangeFormatLength, LONG, []uint32{uint32(preview.Len())}},
+			{XMP, UNDEFINED, []byte(testXMP)},
+			{ExifTag, LONG, []uint32{exifAt}},
+		}},
+		testBlock{offset: rawAt, entries: append([]testEntry{
+			{ImageWidth, SHORT, []uint16{uint16(width)}},
+			{ImageHeight, SHORT, []uint16{uint16(height)}},
+			{BitsPerSample, SHORT, []uint16{bits}},
+			{StripOffsets, LONG, []uint32{stripAt}},
+			{StripByteCounts, LONG, []uint32{uint32(len(strip))}},
+			{CFARepeatPatternDim, SHORT, []uint16{2, 2}},
+			{CFAPattern2, BYTE, []byte{cfaRed, cfaGreen, cfaGreen, cfaBlue}},
+			{SonyRawFileType, SHORT, []uint16{uint16(rawType)}},
+			{BlackLevel2, SHORT, black[:]},
+			{WB_RGGBLevels, SSHORT, []int16{2600, 1024, 1024, 1800}},
+		}, extra...)},
+		testBlock{offset: exifAt, entries: []testEntry{
+			{ExposureTime, RATIONAL, []uint32{1, 250}},
+			{FNumber, RATIONAL, []uint32{28, 10}},
+			{MakerNote, UNDEFINED, make([]byte, 32)},
+		}},
+		testBlock{offset: previewAt, data: preview.Bytes()},
+		testBlock{offset: stripAt, data: strip},
+	)
+}
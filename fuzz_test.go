@@ -0,0 +1,125 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+//fuzzSeeds are small valid documents in both byte orders, including out of line values and a next IFD.
+func fuzzSeeds() [][]byte {
+	entries := []testEntry{
+		{ImageWidth, SHORT, []uint16{6000}},
+		{BitsPerSample, SHORT, []uint16{12, 14}},
+		{StripOffsets, LONG, []uint32{8}},
+		{WB_RGGBLevels, SSHORT, []int16{2600, 1024, 1024, -5}},
+		{FNumber, RATIONAL, []uint32{28, 10}},
+		{Make, ASCII, []byte("SONY\x00")},
+	}
+	return [][]byte{
+		buildTIFF(binary.LittleEndian, testBlock{entries: entries}),
+		buildTIFF(binary.BigEndian, testBlock{entries: entries}),
+		[]byte("II*\x00\x08\x00\x00\x00\x00\x00\x08\x00\x00\x00"),
+	}
+}
+
+func FuzzParseHeader(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseHeader(bytes.NewReader(data))
+		NewFile(bytes.NewReader(data))
+	})
+}
+
+func FuzzExtractMetaData(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := NewFile(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		ifds, _ := file.IFDs()
+		for _, ifd := range ifds {
+			_ = ifd.String()
+			for _, fia := range ifd.FIA {
+				file.ExtractMetaData(int64(fia.Offset), 0)
+			}
+		}
+		ExtractMetaData(bytes.NewReader(data), int64(file.Header.Offset), 0)
+		file.ReadTree()
+	})
+}
+
+func FuzzDecodeConfig(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecodeConfig(bytes.NewReader(data))
+	})
+}
+
+func FuzzDecryptSR2(f *testing.F) {
+	f.Add(make([]byte, 64), uint32(0), uint32(64), uint32(0x44332211))
+	f.Add(make([]byte, 64), uint32(10), uint32(7), uint32(0))
+	f.Fuzz(func(t *testing.T, data []byte, offset, length, key uint32) {
+		buf, err := DecryptSR2Key(bytes.NewReader(data), offset, length, key)
+		if err == nil && len(buf) != int(length) {
+			t.Errorf("expected %v bytes, got %v", length, len(buf))
+		}
+	})
+}
+
+func FuzzLJPEG(f *testing.F) {
+	pix := make([]uint16, 8*4*4)
+	for i := range pix {
+		pix[i] = uint16(i * 509 % 0x4000)
+	}
+	f.Add(encodeLJPEG(pix, 8, 4, 4, 14, 1, 0))
+	f.Add(encodeLJPEG(pix, 8, 4, 4, 14, 6, 8))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		j, err := decodeLJPEG(data, 1<<20)
+		if err == nil && len(j.pix) != j.width*j.height*len(j.components) {
+			t.Errorf("%v samples for %vx%vx%v", len(j.pix), j.width, j.height, len(j.components))
+		}
+	})
+}
+
+func FuzzReadCrawBlock(f *testing.F) {
+	f.Add(make([]byte, pixelBlockSize))
+	f.Add(bytes.Repeat([]byte{0xff}, pixelBlockSize))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < pixelBlockSize {
+			return
+		}
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			block := readCrawBlock(data[:pixelBlockSize], order)
+			pix, err := block.Decompress()
+			if err != nil {
+				continue
+			}
+			if pix[block.maxidx] != block.max || pix[block.minidx] != block.min {
+				t.Errorf("block %v decompressed to %v", block, pix)
+			}
+		}
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+	const width, height = 16, 8
+	f.Add(buildRaw14ARW(f, width, height, make([]uint16, width*height)))
+	f.Add(buildRaw14ARW(f, width, height, make([]uint16, width)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, err := Decode(bytes.NewReader(data))
+		if err == nil && img == nil {
+			t.Error("nil image without an error")
+		}
+	})
+}
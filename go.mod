@@ -0,0 +1,3 @@
+module github.com/tobiash/arw
+
+go 1.18
@@ -0,0 +1,117 @@
+package arw
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+//GPS is the position and time recorded in the GPS IFD.
+type GPS struct {
+	HasPosition bool
+	Latitude    float64 //Decimal degrees, negative south of the equator
+	Longitude   float64 //Decimal degrees, negative west of Greenwich
+
+	HasAltitude bool
+	Altitude    float64 //Metres, negative below sea level
+
+	Time time.Time //UTC, zero when the date or time is missing
+
+	HasDirection  bool
+	Direction     float64 //Degrees clockwise from north
+	MagneticNorth bool    //Direction is relative to magnetic instead of true north
+
+	Datum string
+}
+
+//GPS returns the decoded GPS IFD referenced by IFD0.
+func (f *File) GPS() (*GPS, error) {
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, fia := range ifd0.FIA {
+		if fia.Tag == GPSTag {
+			gps, err := f.ExtractMetaData(int64(fia.Offset), 0)
+			if err != nil {
+				return nil, err
+			}
+			return parseGPS(gps), nil
+		}
+	}
+	return nil, errors.New("no GPS IFD")
+}
+
+//parseGPS decodes the entries of a GPS IFD, entries which are missing or malformed are left out.
+func parseGPS(ifd EXIFIFD) *GPS {
+	var refs = make(map[IFDtag]string)
+	var rats = make(map[IFDtag][]Rational)
+	var altitudeRef byte
+	for i, fia := range ifd.FIA {
+		val := ifd.FIAvals[i]
+		switch {
+		case val.frac != nil:
+			rats[fia.Tag] = *val.frac
+		case fia.Tag == GPSAltitudeRef && val.ascii != nil && len(*val.ascii) > 0:
+			altitudeRef = (*val.ascii)[0]
+		case val.ascii != nil:
+			refs[fia.Tag] = strings.TrimRight(string(*val.ascii), "\x00 ")
+		}
+	}
+
+	gps := &GPS{Datum: refs[GPSMapDatum]}
+
+	lat, latOK := degrees(rats[GPSLatitude])
+	long, longOK := degrees(rats[GPSLongitude])
+	if latOK && longOK {
+		gps.HasPosition = true
+		gps.Latitude, gps.Longitude = lat, long
+		if refs[GPSLatitudeRef] == "S" {
+			gps.Latitude = -lat
+		}
+		if refs[GPSLongitudeRef] == "W" {
+			gps.Longitude = -long
+		}
+	}
+
+	if altitude := rats[GPSAltitude]; len(altitude) > 0 && finite(altitude[0].Float()) {
+		gps.HasAltitude = true
+		gps.Altitude = altitude[0].Float()
+		if altitudeRef == 1 {
+			gps.Altitude = -gps.Altitude
+		}
+	}
+
+	if direction := rats[GPSImgDirection]; len(direction) > 0 && finite(direction[0].Float()) {
+		gps.HasDirection = true
+		gps.Direction = direction[0].Float()
+		gps.MagneticNorth = refs[GPSImgDirectionRef] == "M"
+	}
+
+	//GPSDateStamp is formatted as YYYY:MM:DD, GPSTimeStamp holds hours, minutes and seconds
+	if date, err := time.Parse("2006:01:02", refs[GPSDateStamp]); err == nil {
+		if clock := rats[GPSTimeStamp]; len(clock) == 3 {
+			seconds := clock[0].Float()*3600 + clock[1].Float()*60 + clock[2].Float()
+			if finite(seconds) {
+				gps.Time = date.Add(time.Duration(seconds * float64(time.Second)))
+			}
+		}
+	}
+	return gps
+}
+
+//degrees converts degrees, minutes and seconds to decimal degrees.
+//The rationals are divided in float64, float32 loses a few metres at the precision GPS receivers record.
+func degrees(dms []Rational) (float64, bool) {
+	if len(dms) != 3 {
+		return 0, false
+	}
+	deg := dms[0].Float() + dms[1].Float()/60 + dms[2].Float()/3600
+	return deg, finite(deg)
+}
+
+//finite reports whether f is neither an infinity nor NaN, as results of a zero denominator are.
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
@@ -0,0 +1,85 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGPS(t *testing.T) {
+	entries := []testEntry{
+		{GPSVersionID, BYTE, []byte{2, 3, 0, 0}},
+		{GPSLatitudeRef, ASCII, []byte("S\x00")},
+		{GPSLatitude, RATIONAL, []uint32{33, 1, 51, 1, 3150, 100}},
+		{GPSLongitudeRef, ASCII, []byte("E\x00")},
+		{GPSLongitude, RATIONAL, []uint32{151, 1, 12, 1, 3600, 100}},
+		{GPSAltitudeRef, BYTE, []byte{1}},
+		{GPSAltitude, RATIONAL, []uint32{1234567891, 100000}},
+		{GPSTimeStamp, RATIONAL, []uint32{23, 1, 59, 1, 305, 10}},
+		{GPSImgDirectionRef, ASCII, []byte("M\x00")},
+		{GPSImgDirection, RATIONAL, []uint32{27150, 100}},
+		{GPSMapDatum, ASCII, []byte("WGS-84\x00")},
+		{GPSDateStamp, ASCII, []byte("2022:03:14\x00")},
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		f, err := NewFile(bytes.NewReader(buildGPSTIFF(order, entries)))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		gps, err := f.GPS()
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+
+		near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+		if !gps.HasPosition || !near(gps.Latitude, -(33+51.0/60+31.5/3600)) || !near(gps.Longitude, 151.21) {
+			t.Errorf("%v: unexpected position %v, %v", order, gps.Latitude, gps.Longitude)
+		}
+		//Dividing in float32 would be off by centimetres here
+		if !gps.HasAltitude || math.Abs(gps.Altitude+12345.67891) > 1e-9 {
+			t.Errorf("%v: unexpected altitude %v", order, gps.Altitude)
+		}
+		if !gps.HasDirection || !near(gps.Direction, 271.5) || !gps.MagneticNorth {
+			t.Errorf("%v: unexpected direction %v %v", order, gps.Direction, gps.MagneticNorth)
+		}
+		if expected := time.Date(2022, 3, 14, 23, 59, 30, 5e8, time.UTC); !gps.Time.Equal(expected) {
+			t.Errorf("%v: expected %v, got %v", order, expected, gps.Time)
+		}
+		if gps.Datum != "WGS-84" {
+			t.Errorf("%v: unexpected datum %q", order, gps.Datum)
+		}
+	}
+}
+
+func TestGPSWithoutFix(t *testing.T) {
+	f, err := NewFile(bytes.NewReader(buildGPSTIFF(binary.LittleEndian, []testEntry{
+		{GPSVersionID, BYTE, []byte{2, 3, 0, 0}},
+		{GPSLatitude, RATIONAL, []uint32{33, 0, 51, 1, 3150, 100}},
+		{GPSLongitude, RATIONAL, []uint32{151, 1, 12, 1, 3600, 100}},
+	})))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	gps, err := f.GPS()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if gps.HasPosition || gps.HasAltitude || !gps.Time.IsZero() {
+		t.Errorf("Expected no position, altitude or time: %+v", gps)
+	}
+
+	f, err = NewFile(bytes.NewReader(buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{ImageWidth, SHORT, []uint16{6000}}}})))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := f.GPS(); err == nil {
+		t.Error("Expected an error for a file without GPS IFD")
+	}
+}
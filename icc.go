@@ -0,0 +1,191 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+//ColorProfile selects the primaries and transfer curve of exported images and the ICC profile embedded with them.
+type ColorProfile int
+
+const (
+	SRGB ColorProfile = iota
+	AdobeRGB
+	ProPhotoRGB
+)
+
+func (c ColorProfile) String() string {
+	switch c {
+	case AdobeRGB:
+		return "Adobe RGB (1998)"
+	case ProPhotoRGB:
+		return "ProPhoto RGB"
+	}
+	return "sRGB IEC61966-2.1"
+}
+
+//colorants returns the XYZ of the red, green and blue primaries adapted to D50 as columns, as ICC profiles store them.
+func (c ColorProfile) colorants() [3][3]float64 {
+	switch c {
+	case AdobeRGB:
+		return [3][3]float64{
+			{0.6097559, 0.2052401, 0.1492240},
+			{0.3111242, 0.6256560, 0.0632197},
+			{0.0194811, 0.0608902, 0.7448387},
+		}
+	case ProPhotoRGB:
+		return [3][3]float64{
+			{0.7976749, 0.1351917, 0.0313534},
+			{0.2880402, 0.7118741, 0.0000857},
+			{0.0000000, 0.0000000, 0.8252100},
+		}
+	}
+	return [3][3]float64{
+		{0.4360747, 0.3850649, 0.1430804},
+		{0.2225045, 0.7168786, 0.0606169},
+		{0.0139322, 0.0971045, 0.7141733},
+	}
+}
+
+//encode applies the transfer curve of the colour space to a linear value in [0, 1].
+func (c ColorProfile) encode(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	switch c {
+	case AdobeRGB:
+		return math.Pow(x, 1/adobeGamma)
+	case ProPhotoRGB:
+		if x < 1.0/512 {
+			return x * 16
+		}
+		return math.Pow(x, 1/1.8)
+	}
+	return sRGB(x)
+}
+
+//Adobe RGB (1998) gamma, 2 51/256 as stored in its profile.
+const adobeGamma = 563.0 / 256
+
+//srgbLinear converts an sRGB encoded value in [0, 1] back to linear light.
+func srgbLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+//iccProfile builds a version 2 matrix/TRC display profile for the colour space.
+func iccProfile(c ColorProfile) []byte {
+	m := c.colorants()
+	var trc []byte
+	switch c {
+	case AdobeRGB:
+		trc = iccGamma(adobeGamma)
+	case ProPhotoRGB:
+		trc = iccGamma(1.8)
+	default:
+		curve := make([]uint16, 1024)
+		for i := range curve {
+			curve[i] = uint16(math.Round(srgbLinear(float64(i)/1023) * 0xffff))
+		}
+		trc = iccCurve(curve)
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", iccDescription(c.String())},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9642, 1, 0.8249)},
+		{"rXYZ", iccXYZ(m[0][0], m[1][0], m[2][0])},
+		{"gXYZ", iccXYZ(m[0][1], m[1][1], m[2][1])},
+		{"bXYZ", iccXYZ(m[0][2], m[1][2], m[2][2])},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	//Tag data follows the header and tag table, aligned to 4 bytes, the curves are shared
+	var table, data bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	dataOffset := 128 + 4 + 12*len(tags)
+	offsets := make(map[string]int)
+	for _, tag := range tags {
+		key := string(tag.data)
+		offset, ok := offsets[key]
+		if !ok {
+			offset = dataOffset + data.Len()
+			offsets[key] = offset
+			data.Write(tag.data)
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+		}
+		table.WriteString(tag.sig)
+		binary.Write(&table, binary.BigEndian, uint32(offset))
+		binary.Write(&table, binary.BigEndian, uint32(len(tag.data)))
+	}
+
+	size := 128 + table.Len() + data.Len()
+	var header bytes.Buffer
+	binary.Write(&header, binary.BigEndian, uint32(size))
+	header.WriteString("\x00\x00\x00\x00")       //Preferred CMM
+	header.Write([]byte{2, 0x10, 0, 0})          //Version 2.1
+	header.WriteString("mntrRGB XYZ ")           //Display class, RGB data, XYZ connection space
+	header.Write(make([]byte, 12))               //Creation date
+	header.WriteString("acsp")                   //Signature
+	header.Write(make([]byte, 4+4+4+4+8+4))      //Platform, flags, manufacturer, model, attributes, intent
+	header.Write(iccXYZ(0.9642, 1, 0.8249)[8:])  //D50 illuminant of the connection space
+	header.Write(make([]byte, 128-header.Len())) //Creator and reserved
+
+	return append(append(header.Bytes(), table.Bytes()...), data.Bytes()...)
+}
+
+//s15Fixed16 encodes v as a signed 15.16 fixed point number.
+func s15Fixed16(v float64) uint32 {
+	return uint32(int32(math.Round(v * 65536)))
+}
+
+func iccXYZ(x, y, z float64) []byte {
+	var b bytes.Buffer
+	b.WriteString("XYZ \x00\x00\x00\x00")
+	binary.Write(&b, binary.BigEndian, []uint32{s15Fixed16(x), s15Fixed16(y), s15Fixed16(z)})
+	return b.Bytes()
+}
+
+//iccGamma is a curve of a single gamma value as u8Fixed8.
+func iccGamma(gamma float64) []byte {
+	var b bytes.Buffer
+	b.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, uint16(math.Round(gamma*256)))
+	return b.Bytes()
+}
+
+func iccCurve(curve []uint16) []byte {
+	var b bytes.Buffer
+	b.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&b, binary.BigEndian, uint32(len(curve)))
+	binary.Write(&b, binary.BigEndian, curve)
+	return b.Bytes()
+}
+
+func iccText(text string) []byte {
+	return []byte("text\x00\x00\x00\x00" + text + "\x00")
+}
+
+//iccDescription is a textDescriptionType with only the ASCII description filled in.
+func iccDescription(text string) []byte {
+	var b bytes.Buffer
+	b.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&b, binary.BigEndian, uint32(len(text)+1))
+	b.WriteString(text + "\x00")
+	b.Write(make([]byte, 4+4+2+1+67)) //Empty Unicode and ScriptCode descriptions
+	return b.Bytes()
+}
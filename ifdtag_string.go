@@ -4,161 +4,223 @@ package arw
 
 import "fmt"
 
-const _IFDtag_name = "NewSubFileTypeImageWidthImageHeightBitsPerSampleCompressionPhotometricInterpretationImageDescriptionMakeModelStripOffsetsOrientationSamplesPerPixelRowsPerStripStripByteCountsXResolutionYResolutionPlanarConfigurationResolutionUnitSoftwareDateTimeWhitepointPrimaryChromaticitiesSubIFDsJPEGInterchangeFormatJPEGInterchangeFormatLengthYCbCrCoefficientsYCbCrPositioningXMPShotInfoSonyRawFileTypeSonyCurveSR2SubIFDOffsetSR2SubIFDLengthSR2SubIFDKeyIDC_IFDIDC2_IFDMRWInfoBlackLevelWB_GRBGLevelsAutoWB_GRBGLevelsBlackLevel2WB_RGGBLevelsWB_RGBLevelsDaylightWB_RGBLevelsCloudyWB_RGBLevelsTungstenWB_RGBLevelsFlashWB_RGBLevels4500KWB_RGBLevelsFluorescentMaxApertureAtMaxFocalMaxApertureAtMinFocalMaxFocalLengthMinFocalLengthSR2DataIFDColorMatrixWB_RGBLevelsDaylight2WB_RGBLevelsCloudy2WB_RGBLevelsTungsten2WB_RGBLevelsFlash2WB_RGBLevels4500K2WB_RGBLevelsShade2WB_RGBLevelsFluorescent2WB_RGBLevelsFluorescentP1WB_RGBLevelsFluorescentP2WB_RGBLevelsFluorescentM1WB_RGBLevels8500KWB_RGBLevels6000KWB_RGBLevels3200KWB_RGBLevels2500KWhiteLevelVignettingCorrParamsChromaticAberrationCorrParamsDistortionCorrParamsCFARepeatPatternDimCFAPattern2ExposureTimeFNumberExifTagExposureProgramSpectralSensitivityGPSTagISOSpeedRatingsOECFSensitivityTypeRecommendedExposureIndexExifVersionDateTimeOriginalDateTimeDigitizedOffsetTimeOffsetTimeOriginalOffsetTimeDigitizedComponentsConfigurationCompressedBitsPerPixelShutterSpeedValueApertureValueBrightnessValueExposureBiasValueMaxApertureValueSubjectDistanceMeteringModeLightSourceFlashFocalLengthSubjectAreaMakerNoteUserCommentSubsecTimeSubsecTimeOriginalSubsecTimeDigitizedTag9400FlashpixVersionColorSpacePixelXDimensionPixelYDimensionRelatedSoundFileInteroperabilityTagFlashEnergySpatialFrequencyResponseFocalPlaneXResolutionFocalPlaneYResolutionFocalPlaneResolutionUnitSubjectLocationExposureIndexSensingMethodFileSourceSceneTypeCFAPatternCustomRenderedExposureModeWhiteBalanceDigitalZoomRatioFocalLengthIn35mmFilmSceneCaptureTypeGainControlContrastSaturationSharpnessDeviceSettingDescriptionSubjectDistanceRangeImageUniqueIDLensSpecificationLensModelGammaFileFormatSonyModelIDCreativeStyleLensSpecFullImageSizePreviewImageSizePrintImageMatchingDefaultCropOriginDefaultCropSizeDNGPrivateData"
+const _IFDtag_name = "GPSVersionIDGPSLatitudeRefGPSLatitudeGPSLongitudeRefGPSLongitudeGPSAltitudeRefGPSAltitudeGPSTimeStampGPSSatellitesGPSStatusGPSMeasureModeGPSDOPGPSSpeedRefGPSSpeedGPSTrackRefGPSTrackGPSImgDirectionRefGPSImgDirectionGPSMapDatumGPSDestLatitudeRefGPSDestLatitudeGPSDestLongitudeRefGPSDestLongitudeGPSDestBearingRefGPSDestBearingGPSDestDistanceRefGPSDestDistanceGPSProcessingMethodGPSAreaInformationGPSDateStampGPSDifferentialGPSHPositioningErrorNewSubFileTypeImageWidthImageHeightBitsPerSampleCompressionPhotometricInterpretationImageDescriptionMakeModelStripOffsetsOrientationSamplesPerPixelRowsPerStripStripByteCountsXResolutionYResolutionPlanarConfigurationResolutionUnitSoftwareDateTimeArtistWhitepointPrimaryChromaticitiesTileWidthTileLengthTileOffsetsTileByteCountsSubIFDsJPEGInterchangeFormatJPEGInterchangeFormatLengthYCbCrCoefficientsYCbCrPositioningXMPPreviewImageTag2010ShotInfoSonyRawFileTypeSonyCurveSR2SubIFDOffsetSR2SubIFDLengthSR2SubIFDKeyIDC_IFDIDC2_IFDMRWInfoBlackLevelWB_GRBGLevelsAutoWB_GRBGLevelsBlackLevel2WB_RGGBLevelsWB_RGBLevelsDaylightWB_RGBLevelsCloudyWB_RGBLevelsTungstenWB_RGBLevelsFlashWB_RGBLevels4500KWB_RGBLevelsFluorescentMaxApertureAtMaxFocalMaxApertureAtMinFocalMaxFocalLengthMinFocalLengthSR2DataIFDColorMatrixWB_RGBLevelsDaylight2WB_RGBLevelsCloudy2WB_RGBLevelsTungsten2WB_RGBLevelsFlash2WB_RGBLevels4500K2WB_RGBLevelsShade2WB_RGBLevelsFluorescent2WB_RGBLevelsFluorescentP1WB_RGBLevelsFluorescentP2WB_RGBLevelsFluorescentM1WB_RGBLevels8500KWB_RGBLevels6000KWB_RGBLevels3200KWB_RGBLevels2500KWhiteLevelVignettingCorrParamsChromaticAberrationCorrParamsDistortionCorrParamsCFARepeatPatternDimCFAPattern2CopyrightExposureTimeFNumberExifTagInterColorProfileExposureProgramSpectralSensitivityGPSTagISOSpeedRatingsOECFSensitivityTypeRecommendedExposureIndexExifVersionDateTimeOriginalDateTimeDigitizedOffsetTimeOffsetTimeOriginalOffsetTimeDigitizedTag9050ComponentsConfigurationCompressedBitsPerPixelShutterSpeedValueApertureValueBrightnessValueExposureBiasValueMaxApertureValueSubjectDistanceMeteringModeLightSourceFlashFocalLengthSubjectAreaMakerNoteUserCommentSubsecTimeSubsecTimeOriginalSubsecTimeDigitizedTag9400Tag9406FlashpixVersionColorSpacePixelXDimensionPixelYDimensionRelatedSoundFileInteroperabilityTagFlashEnergySpatialFrequencyResponseFocalPlaneXResolutionFocalPlaneYResolutionFocalPlaneResolutionUnitSubjectLocationExposureIndexSensingMethodFileSourceSceneTypeCFAPatternCustomRenderedExposureModeWhiteBalanceDigitalZoomRatioFocalLengthIn35mmFilmSceneCaptureTypeGainControlContrastSaturationSharpnessDeviceSettingDescriptionSubjectDistanceRangeImageUniqueIDLensSpecificationLensModelGammaFileFormatSonyModelIDCreativeStyleLensSpecFullImageSizePreviewImageSizePrintImageMatchingDNGVersionDNGBackwardVersionUniqueCameraModelLocalizedCameraModelCFAPlaneColorCFALayoutLinearizationTableBlackLevelRepeatDimDNGBlackLevelDNGWhiteLevelDefaultScaleDefaultCropOriginDefaultCropSizeColorMatrix1ColorMatrix2AnalogBalanceAsShotNeutralBaselineExposureDNGPrivateDataCalibrationIlluminant1CalibrationIlluminant2OriginalRawFileName"
 
 var _IFDtag_map = map[IFDtag]string{
-	254:   _IFDtag_name[0:14],
-	256:   _IFDtag_name[14:24],
-	257:   _IFDtag_name[24:35],
-	258:   _IFDtag_name[35:48],
-	259:   _IFDtag_name[48:59],
-	262:   _IFDtag_name[59:84],
-	270:   _IFDtag_name[84:100],
-	271:   _IFDtag_name[100:104],
-	272:   _IFDtag_name[104:109],
-	273:   _IFDtag_name[109:121],
-	274:   _IFDtag_name[121:132],
-	277:   _IFDtag_name[132:147],
-	278:   _IFDtag_name[147:159],
-	279:   _IFDtag_name[159:174],
-	282:   _IFDtag_name[174:185],
-	283:   _IFDtag_name[185:196],
-	284:   _IFDtag_name[196:215],
-	296:   _IFDtag_name[215:229],
-	305:   _IFDtag_name[229:237],
-	306:   _IFDtag_name[237:245],
-	318:   _IFDtag_name[245:255],
-	319:   _IFDtag_name[255:276],
-	330:   _IFDtag_name[276:283],
-	513:   _IFDtag_name[283:304],
-	514:   _IFDtag_name[304:331],
-	529:   _IFDtag_name[331:348],
-	531:   _IFDtag_name[348:364],
-	700:   _IFDtag_name[364:367],
-	12288: _IFDtag_name[367:375],
-	28672: _IFDtag_name[375:390],
-	28688: _IFDtag_name[390:399],
-	29184: _IFDtag_name[399:414],
-	29185: _IFDtag_name[414:429],
-	29217: _IFDtag_name[429:441],
-	29248: _IFDtag_name[441:448],
-	29249: _IFDtag_name[448:456],
-	29264: _IFDtag_name[456:463],
-	29440: _IFDtag_name[463:473],
-	29442: _IFDtag_name[473:490],
-	29443: _IFDtag_name[490:503],
-	29456: _IFDtag_name[503:514],
-	29459: _IFDtag_name[514:527],
-	29824: _IFDtag_name[527:547],
-	29825: _IFDtag_name[547:565],
-	29826: _IFDtag_name[565:585],
-	29827: _IFDtag_name[585:602],
-	29828: _IFDtag_name[602:619],
-	29830: _IFDtag_name[619:642],
-	29856: _IFDtag_name[642:663],
-	29857: _IFDtag_name[663:684],
-	29858: _IFDtag_name[684:698],
-	29859: _IFDtag_name[698:712],
-	29888: _IFDtag_name[712:722],
-	30720: _IFDtag_name[722:733],
-	30752: _IFDtag_name[733:754],
-	30753: _IFDtag_name[754:773],
-	30754: _IFDtag_name[773:794],
-	30755: _IFDtag_name[794:812],
-	30756: _IFDtag_name[812:830],
-	30757: _IFDtag_name[830:848],
-	30758: _IFDtag_name[848:872],
-	30759: _IFDtag_name[872:897],
-	30760: _IFDtag_name[897:922],
-	30761: _IFDtag_name[922:947],
-	30762: _IFDtag_name[947:964],
-	30763: _IFDtag_name[964:981],
-	30764: _IFDtag_name[981:998],
-	30765: _IFDtag_name[998:1015],
-	30847: _IFDtag_name[1015:1025],
-	31101: _IFDtag_name[1025:1045],
-	31104: _IFDtag_name[1045:1074],
-	31106: _IFDtag_name[1074:1094],
-	33421: _IFDtag_name[1094:1113],
-	33422: _IFDtag_name[1113:1124],
-	33434: _IFDtag_name[1124:1136],
-	33437: _IFDtag_name[1136:1143],
-	34665: _IFDtag_name[1143:1150],
-	34850: _IFDtag_name[1150:1165],
-	34852: _IFDtag_name[1165:1184],
-	34853: _IFDtag_name[1184:1190],
-	34855: _IFDtag_name[1190:1205],
-	34856: _IFDtag_name[1205:1209],
-	34864: _IFDtag_name[1209:1224],
-	34866: _IFDtag_name[1224:1248],
-	36864: _IFDtag_name[1248:1259],
-	36867: _IFDtag_name[1259:1275],
-	36868: _IFDtag_name[1275:1292],
-	36880: _IFDtag_name[1292:1302],
-	36881: _IFDtag_name[1302:1320],
-	36882: _IFDtag_name[1320:1339],
-	37121: _IFDtag_name[1339:1362],
-	37122: _IFDtag_name[1362:1384],
-	37377: _IFDtag_name[1384:1401],
-	37378: _IFDtag_name[1401:1414],
-	37379: _IFDtag_name[1414:1429],
-	37380: _IFDtag_name[1429:1446],
-	37381: _IFDtag_name[1446:1462],
-	37382: _IFDtag_name[1462:1477],
-	37383: _IFDtag_name[1477:1489],
-	37384: _IFDtag_name[1489:1500],
-	37385: _IFDtag_name[1500:1505],
-	37386: _IFDtag_name[1505:1516],
-	37396: _IFDtag_name[1516:1527],
-	37500: _IFDtag_name[1527:1536],
-	37510: _IFDtag_name[1536:1547],
-	37520: _IFDtag_name[1547:1557],
-	37521: _IFDtag_name[1557:1575],
-	37522: _IFDtag_name[1575:1594],
-	37888: _IFDtag_name[1594:1601],
-	40960: _IFDtag_name[1601:1616],
-	40961: _IFDtag_name[1616:1626],
-	40962: _IFDtag_name[1626:1641],
-	40963: _IFDtag_name[1641:1656],
-	40964: _IFDtag_name[1656:1672],
-	40965: _IFDtag_name[1672:1691],
-	41483: _IFDtag_name[1691:1702],
-	41484: _IFDtag_name[1702:1726],
-	41486: _IFDtag_name[1726:1747],
-	41487: _IFDtag_name[1747:1768],
-	41488: _IFDtag_name[1768:1792],
-	41492: _IFDtag_name[1792:1807],
-	41493: _IFDtag_name[1807:1820],
-	41495: _IFDtag_name[1820:1833],
-	41728: _IFDtag_name[1833:1843],
-	41729: _IFDtag_name[1843:1852],
-	41730: _IFDtag_name[1852:1862],
-	41985: _IFDtag_name[1862:1876],
-	41986: _IFDtag_name[1876:1888],
-	41987: _IFDtag_name[1888:1900],
-	41988: _IFDtag_name[1900:1916],
-	41989: _IFDtag_name[1916:1937],
-	41990: _IFDtag_name[1937:1953],
-	41991: _IFDtag_name[1953:1964],
-	41992: _IFDtag_name[1964:1972],
-	41993: _IFDtag_name[1972:1982],
-	41994: _IFDtag_name[1982:1991],
-	41995: _IFDtag_name[1991:2015],
-	41996: _IFDtag_name[2015:2035],
-	42016: _IFDtag_name[2035:2048],
-	42034: _IFDtag_name[2048:2065],
-	42036: _IFDtag_name[2065:2074],
-	42240: _IFDtag_name[2074:2079],
-	45056: _IFDtag_name[2079:2089],
-	45057: _IFDtag_name[2089:2100],
-	45088: _IFDtag_name[2100:2113],
-	45098: _IFDtag_name[2113:2121],
-	45099: _IFDtag_name[2121:2134],
-	45100: _IFDtag_name[2134:2150],
-	50341: _IFDtag_name[2150:2168],
-	50719: _IFDtag_name[2168:2185],
-	50720: _IFDtag_name[2185:2200],
-	50740: _IFDtag_name[2200:2214],
+	0:     _IFDtag_name[0:12],
+	1:     _IFDtag_name[12:26],
+	2:     _IFDtag_name[26:37],
+	3:     _IFDtag_name[37:52],
+	4:     _IFDtag_name[52:64],
+	5:     _IFDtag_name[64:78],
+	6:     _IFDtag_name[78:89],
+	7:     _IFDtag_name[89:101],
+	8:     _IFDtag_name[101:114],
+	9:     _IFDtag_name[114:123],
+	10:    _IFDtag_name[123:137],
+	11:    _IFDtag_name[137:143],
+	12:    _IFDtag_name[143:154],
+	13:    _IFDtag_name[154:162],
+	14:    _IFDtag_name[162:173],
+	15:    _IFDtag_name[173:181],
+	16:    _IFDtag_name[181:199],
+	17:    _IFDtag_name[199:214],
+	18:    _IFDtag_name[214:225],
+	19:    _IFDtag_name[225:243],
+	20:    _IFDtag_name[243:258],
+	21:    _IFDtag_name[258:277],
+	22:    _IFDtag_name[277:293],
+	23:    _IFDtag_name[293:310],
+	24:    _IFDtag_name[310:324],
+	25:    _IFDtag_name[324:342],
+	26:    _IFDtag_name[342:357],
+	27:    _IFDtag_name[357:376],
+	28:    _IFDtag_name[376:394],
+	29:    _IFDtag_name[394:406],
+	30:    _IFDtag_name[406:421],
+	31:    _IFDtag_name[421:441],
+	254:   _IFDtag_name[441:455],
+	256:   _IFDtag_name[455:465],
+	257:   _IFDtag_name[465:476],
+	258:   _IFDtag_name[476:489],
+	259:   _IFDtag_name[489:500],
+	262:   _IFDtag_name[500:525],
+	270:   _IFDtag_name[525:541],
+	271:   _IFDtag_name[541:545],
+	272:   _IFDtag_name[545:550],
+	273:   _IFDtag_name[550:562],
+	274:   _IFDtag_name[562:573],
+	277:   _IFDtag_name[573:588],
+	278:   _IFDtag_name[588:600],
+	279:   _IFDtag_name[600:615],
+	282:   _IFDtag_name[615:626],
+	283:   _IFDtag_name[626:637],
+	284:   _IFDtag_name[637:656],
+	296:   _IFDtag_name[656:670],
+	305:   _IFDtag_name[670:678],
+	306:   _IFDtag_name[678:686],
+	315:   _IFDtag_name[686:692],
+	318:   _IFDtag_name[692:702],
+	319:   _IFDtag_name[702:723],
+	322:   _IFDtag_name[723:732],
+	323:   _IFDtag_name[732:742],
+	324:   _IFDtag_name[742:753],
+	325:   _IFDtag_name[753:767],
+	330:   _IFDtag_name[767:774],
+	513:   _IFDtag_name[774:795],
+	514:   _IFDtag_name[795:822],
+	529:   _IFDtag_name[822:839],
+	531:   _IFDtag_name[839:855],
+	700:   _IFDtag_name[855:858],
+	8193:  _IFDtag_name[858:870],
+	8208:  _IFDtag_name[870:877],
+	12288: _IFDtag_name[877:885],
+	28672: _IFDtag_name[885:900],
+	28688: _IFDtag_name[900:909],
+	29184: _IFDtag_name[909:924],
+	29185: _IFDtag_name[924:939],
+	29217: _IFDtag_name[939:951],
+	29248: _IFDtag_name[951:958],
+	29249: _IFDtag_name[958:966],
+	29264: _IFDtag_name[966:973],
+	29440: _IFDtag_name[973:983],
+	29442: _IFDtag_name[983:1000],
+	29443: _IFDtag_name[1000:1013],
+	29456: _IFDtag_name[1013:1024],
+	29459: _IFDtag_name[1024:1037],
+	29824: _IFDtag_name[1037:1057],
+	29825: _IFDtag_name[1057:1075],
+	29826: _IFDtag_name[1075:1095],
+	29827: _IFDtag_name[1095:1112],
+	29828: _IFDtag_name[1112:1129],
+	29830: _IFDtag_name[1129:1152],
+	29856: _IFDtag_name[1152:1173],
+	29857: _IFDtag_name[1173:1194],
+	29858: _IFDtag_name[1194:1208],
+	29859: _IFDtag_name[1208:1222],
+	29888: _IFDtag_name[1222:1232],
+	30720: _IFDtag_name[1232:1243],
+	30752: _IFDtag_name[1243:1264],
+	30753: _IFDtag_name[1264:1283],
+	30754: _IFDtag_name[1283:1304],
+	30755: _IFDtag_name[1304:1322],
+	30756: _IFDtag_name[1322:1340],
+	30757: _IFDtag_name[1340:1358],
+	30758: _IFDtag_name[1358:1382],
+	30759: _IFDtag_name[1382:1407],
+	30760: _IFDtag_name[1407:1432],
+	30761: _IFDtag_name[1432:1457],
+	30762: _IFDtag_name[1457:1474],
+	30763: _IFDtag_name[1474:1491],
+	30764: _IFDtag_name[1491:1508],
+	30765: _IFDtag_name[1508:1525],
+	30847: _IFDtag_name[1525:1535],
+	31101: _IFDtag_name[1535:1555],
+	31104: _IFDtag_name[1555:1584],
+	31106: _IFDtag_name[1584:1604],
+	33421: _IFDtag_name[1604:1623],
+	33422: _IFDtag_name[1623:1634],
+	33432: _IFDtag_name[1634:1643],
+	33434: _IFDtag_name[1643:1655],
+	33437: _IFDtag_name[1655:1662],
+	34665: _IFDtag_name[1662:1669],
+	34675: _IFDtag_name[1669:1686],
+	34850: _IFDtag_name[1686:1701],
+	34852: _IFDtag_name[1701:1720],
+	34853: _IFDtag_name[1720:1726],
+	34855: _IFDtag_name[1726:1741],
+	34856: _IFDtag_name[1741:1745],
+	34864: _IFDtag_name[1745:1760],
+	34866: _IFDtag_name[1760:1784],
+	36864: _IFDtag_name[1784:1795],
+	36867: _IFDtag_name[1795:1811],
+	36868: _IFDtag_name[1811:1828],
+	36880: _IFDtag_name[1828:1838],
+	36881: _IFDtag_name[1838:1856],
+	36882: _IFDtag_name[1856:1875],
+	36944: _IFDtag_name[1875:1882],
+	37121: _IFDtag_name[1882:1905],
+	37122: _IFDtag_name[1905:1927],
+	37377: _IFDtag_name[1927:1944],
+	37378: _IFDtag_name[1944:1957],
+	37379: _IFDtag_name[1957:1972],
+	37380: _IFDtag_name[1972:1989],
+	37381: _IFDtag_name[1989:2005],
+	37382: _IFDtag_name[2005:2020],
+	37383: _IFDtag_name[2020:2032],
+	37384: _IFDtag_name[2032:2043],
+	37385: _IFDtag_name[2043:2048],
+	37386: _IFDtag_name[2048:2059],
+	37396: _IFDtag_name[2059:2070],
+	37500: _IFDtag_name[2070:2079],
+	37510: _IFDtag_name[2079:2090],
+	37520: _IFDtag_name[2090:2100],
+	37521: _IFDtag_name[2100:2118],
+	37522: _IFDtag_name[2118:2137],
+	37888: _IFDtag_name[2137:2144],
+	37894: _IFDtag_name[2144:2151],
+	40960: _IFDtag_name[2151:2166],
+	40961: _IFDtag_name[2166:2176],
+	40962: _IFDtag_name[2176:2191],
+	40963: _IFDtag_name[2191:2206],
+	40964: _IFDtag_name[2206:2222],
+	40965: _IFDtag_name[2222:2241],
+	41483: _IFDtag_name[2241:2252],
+	41484: _IFDtag_name[2252:2276],
+	41486: _IFDtag_name[2276:2297],
+	41487: _IFDtag_name[2297:2318],
+	41488: _IFDtag_name[2318:2342],
+	41492: _IFDtag_name[2342:2357],
+	41493: _IFDtag_name[2357:2370],
+	41495: _IFDtag_name[2370:2383],
+	41728: _IFDtag_name[2383:2393],
+	41729: _IFDtag_name[2393:2402],
+	41730: _IFDtag_name[2402:2412],
+	41985: _IFDtag_name[2412:2426],
+	41986: _IFDtag_name[2426:2438],
+	41987: _IFDtag_name[2438:2450],
+	41988: _IFDtag_name[2450:2466],
+	41989: _IFDtag_name[2466:2487],
+	41990: _IFDtag_name[2487:2503],
+	41991: _IFDtag_name[2503:2514],
+	41992: _IFDtag_name[2514:2522],
+	41993: _IFDtag_name[2522:2532],
+	41994: _IFDtag_name[2532:2541],
+	41995: _IFDtag_name[2541:2565],
+	41996: _IFDtag_name[2565:2585],
+	42016: _IFDtag_name[2585:2598],
+	42034: _IFDtag_name[2598:2615],
+	42036: _IFDtag_name[2615:2624],
+	42240: _IFDtag_name[2624:2629],
+	45056: _IFDtag_name[2629:2639],
+	45057: _IFDtag_name[2639:2650],
+	45088: _IFDtag_name[2650:2663],
+	45098: _IFDtag_name[2663:2671],
+	45099: _IFDtag_name[2671:2684],
+	45100: _IFDtag_name[2684:2700],
+	50341: _IFDtag_name[2700:2718],
+	50706: _IFDtag_name[2718:2728],
+	50707: _IFDtag_name[2728:2746],
+	50708: _IFDtag_name[2746:2763],
+	50709: _IFDtag_name[2763:2783],
+	50710: _IFDtag_name[2783:2796],
+	50711: _IFDtag_name[2796:2805],
+	50712: _IFDtag_name[2805:2823],
+	50713: _IFDtag_name[2823:2842],
+	50714: _IFDtag_name[2842:2855],
+	50717: _IFDtag_name[2855:2868],
+	50718: _IFDtag_name[2868:2880],
+	50719: _IFDtag_name[2880:2897],
+	50720: _IFDtag_name[2897:2912],
+	50721: _IFDtag_name[2912:2924],
+	50722: _IFDtag_name[2924:2936],
+	50727: _IFDtag_name[2936:2949],
+	50728: _IFDtag_name[2949:2962],
+	50730: _IFDtag_name[2962:2978],
+	50740: _IFDtag_name[2978:2992],
+	50778: _IFDtag_name[2992:3014],
+	50779: _IFDtag_name[3014:3036],
+	50827: _IFDtag_name[3036:3055],
 }
 
 func (i IFDtag) String() string {
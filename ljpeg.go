@@ -0,0 +1,391 @@
+package arw
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Markers used by lossless JPEG, ITU T.81 Table B.1
+const (
+	markerSOF3 = 0xc3
+	markerDHT  = 0xc4
+	markerRST0 = 0xd0
+	markerRST7 = 0xd7
+	markerSOI  = 0xd8
+	markerEOI  = 0xd9
+	markerSOS  = 0xda
+	markerDRI  = 0xdd
+)
+
+//ljpegHuffman is a decoding table as described in ITU T.81 Annex F.2.2.3, with an 8 bit lookup for short codes.
+type ljpegHuffman struct {
+	maxcode [17]int32
+	mincode [17]int32
+	valptr  [17]int
+	vals    []uint8
+	lookup  [256]uint16 //code length in the high byte, value in the low byte, 0 if the code is longer than 8 bits
+}
+
+type ljpegComponent struct {
+	id    uint8
+	table *ljpegHuffman
+}
+
+//ljpeg is a single decoded lossless JPEG (process 14) frame.
+type ljpeg struct {
+	precision  uint
+	width      int
+	height     int
+	components []ljpegComponent
+	predictor  int
+	transform  uint
+	restart    int
+	tables     [4]*ljpegHuffman
+
+	//pix holds the samples with interleaved components, width*len(components) per line.
+	pix []uint16
+}
+
+//decodeLJPEG decodes a lossless JPEG stream as used for the tiles of Sony's lossless compressed ARW.
+//Frames of more than maxSamples samples, counting every component, are rejected before they are allocated.
+func decodeLJPEG(buf []byte, maxSamples int) (*ljpeg, error) {
+	var j ljpeg
+	if len(buf) < 2 || buf[0] != 0xff || buf[1] != markerSOI {
+		return nil, errors.New("missing SOI marker in lossless JPEG")
+	}
+
+	pos := 2
+	for pos+4 <= len(buf) {
+		if buf[pos] != 0xff {
+			return nil, errors.New("expected marker at offset " + fmt.Sprint(pos))
+		}
+		marker := buf[pos+1]
+		if marker == 0xff { //Fill byte
+			pos++
+			continue
+		}
+		if marker == markerEOI {
+			break
+		}
+
+		length := int(buf[pos+2])<<8 | int(buf[pos+3])
+		if length < 2 || pos+2+length > len(buf) {
+			return nil, errors.New("invalid segment length for marker " + fmt.Sprintf("%#x", marker))
+		}
+		segment := buf[pos+4 : pos+2+length]
+		pos += 2 + length
+
+		var err error
+		switch marker {
+		case markerSOF3:
+			err = j.parseSOF(segment)
+		case markerDHT:
+			err = j.parseDHT(segment)
+		case markerDRI:
+			if len(segment) < 2 {
+				return nil, errors.New("short DRI segment")
+			}
+			j.restart = int(segment[0])<<8 | int(segment[1])
+		case markerSOS:
+			if err = j.parseSOS(segment); err != nil {
+				return nil, err
+			}
+			return &j, j.decodeScan(buf[pos:], maxSamples)
+		default:
+			if marker >= 0xc0 && marker <= 0xcf && marker != markerDHT && marker != 0xc8 && marker != 0xcc {
+				return nil, errors.New("unsupported JPEG process, marker: " + fmt.Sprintf("%#x", marker))
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("lossless JPEG without scan")
+}
+
+func (j *ljpeg) parseSOF(s []byte) error {
+	if len(s) < 6 {
+		return errors.New("short SOF3 segment")
+	}
+	j.precision = uint(s[0])
+	j.height = int(s[1])<<8 | int(s[2])
+	j.width = int(s[3])<<8 | int(s[4])
+	count := int(s[5])
+	if j.precision < 2 || j.precision > 16 {
+		return errors.New("invalid lossless JPEG precision: " + fmt.Sprint(j.precision))
+	}
+	if count == 0 || len(s) < 6+count*3 {
+		return errors.New("invalid component count in SOF3 segment")
+	}
+	j.components = make([]ljpegComponent, count)
+	for i := range j.components {
+		j.components[i].id = s[6+i*3]
+		if s[7+i*3] != 0x11 {
+			return errors.New("subsampled lossless JPEG components are not supported")
+		}
+	}
+	return nil
+}
+
+func (j *ljpeg) parseDHT(s []byte) error {
+	for len(s) > 0 {
+		if len(s) < 17 {
+			return errors.New("short DHT segment")
+		}
+		class, id := s[0]>>4, s[0]&0x0f
+		if class != 0 || id > 3 {
+			return errors.New("invalid huffman table in DHT segment")
+		}
+
+		var h ljpegHuffman
+		var counts [17]int
+		total := 0
+		for l := 1; l <= 16; l++ {
+			counts[l] = int(s[l])
+			total += counts[l]
+		}
+		if total > 256 || len(s) < 17+total {
+			return errors.New("invalid huffman table size in DHT segment")
+		}
+		h.vals = append([]uint8(nil), s[17:17+total]...)
+		s = s[17+total:]
+
+		code, k := int32(0), 0
+		for l := 1; l <= 16; l++ {
+			h.valptr[l] = k
+			h.mincode[l] = code
+			for i := 0; i < counts[l]; i++ {
+				if code >= 1<<uint(l) {
+					return errors.New("oversubscribed huffman table in DHT segment")
+				}
+				if l <= 8 {
+					shift := uint(8 - l)
+					for fill := 0; fill < 1<<shift; fill++ {
+						h.lookup[int(code)<<shift|fill] = uint16(l)<<8 | uint16(h.vals[k])
+					}
+				}
+				code++
+				k++
+			}
+			if counts[l] == 0 {
+				h.maxcode[l] = -1
+			} else {
+				h.maxcode[l] = code - 1
+			}
+			code <<= 1
+		}
+		j.tables[id] = &h
+	}
+	return nil
+}
+
+func (j *ljpeg) parseSOS(s []byte) error {
+	if len(j.components) == 0 {
+		return errors.New("SOS segment before SOF3")
+	}
+	if len(s) < 1 || int(s[0]) != len(j.components) || len(s) < 1+len(j.components)*2+3 {
+		return errors.New("scans with a subset of the components are not supported")
+	}
+	for i := range j.components {
+		if s[1+i*2] != j.components[i].id {
+			return errors.New("scan component order differs from frame")
+		}
+		id := s[2+i*2] >> 4
+		if id > 3 || j.tables[id] == nil {
+			return errors.New("scan references undefined huffman table")
+		}
+		j.components[i].table = j.tables[id]
+	}
+	rest := s[1+len(j.components)*2:]
+	j.predictor = int(rest[0])
+	j.transform = uint(rest[2] & 0x0f)
+	if j.predictor < 1 || j.predictor > 7 {
+		return errors.New("invalid lossless JPEG predictor: " + fmt.Sprint(j.predictor))
+	}
+	if j.transform >= j.precision {
+		return errors.New("invalid lossless JPEG point transform")
+	}
+	return nil
+}
+
+//decodeScan decodes the entropy coded data, ITU T.81 Annex H.
+func (j *ljpeg) decodeScan(data []byte, maxSamples int) error {
+	comps := len(j.components)
+	line := j.width * comps
+	//Every sample takes at least a single bit of huffman code, so the data bounds the frame as well
+	if line*j.height > maxSamples || line*j.height > len(data)*8 {
+		return errors.New("lossless JPEG frame too large: " + fmt.Sprint(j.width, "x", j.height, "x", comps))
+	}
+	j.pix = make([]uint16, line*j.height)
+	if line == 0 {
+		return nil
+	}
+
+	bits := ljpegBits{buf: data}
+	initial := int32(1) << (j.precision - j.transform - 1)
+	firstLine, start := true, 0
+	mcus := 0
+
+	for y := 0; y < j.height; y++ {
+		cur := j.pix[y*line : (y+1)*line]
+		var prev []uint16
+		if y > 0 {
+			prev = j.pix[(y-1)*line : y*line]
+		}
+
+		for x := 0; x < j.width; x++ {
+			if j.restart > 0 && mcus == j.restart {
+				if err := bits.restart(); err != nil {
+					return err
+				}
+				mcus = 0
+				firstLine, start = true, x
+			}
+
+			for c := 0; c < comps; c++ {
+				diff, err := bits.diff(j.components[c].table)
+				if err != nil {
+					return fmt.Errorf("lossless JPEG line %v column %v: %v", y, x, err)
+				}
+
+				var pred int32
+				i := x*comps + c
+				switch {
+				case firstLine && x == start:
+					pred = initial
+				case firstLine:
+					pred = int32(cur[i-comps])
+				case x == 0:
+					pred = int32(prev[i])
+				default:
+					ra, rb, rc := int32(cur[i-comps]), int32(prev[i]), int32(prev[i-comps])
+					switch j.predictor {
+					case 1:
+						pred = ra
+					case 2:
+						pred = rb
+					case 3:
+						pred = rc
+					case 4:
+						pred = ra + rb - rc
+					case 5:
+						pred = ra + ((rb - rc) >> 1)
+					case 6:
+						pred = rb + ((ra - rc) >> 1)
+					case 7:
+						pred = (ra + rb) >> 1
+					}
+				}
+				cur[i] = uint16(pred + diff)
+			}
+			mcus++
+		}
+		firstLine, start = false, 0
+	}
+
+	if j.transform > 0 {
+		for i := range j.pix {
+			j.pix[i] <<= j.transform
+		}
+	}
+	return nil
+}
+
+//ljpegBits reads entropy coded data, removing stuffed zero bytes and stopping at markers.
+type ljpegBits struct {
+	buf    []byte
+	pos    int
+	acc    uint64
+	n      uint
+	marker bool
+}
+
+func (b *ljpegBits) fill() {
+	for b.n <= 56 {
+		var next byte
+		if !b.marker && b.pos < len(b.buf) {
+			next = b.buf[b.pos]
+			if next == 0xff {
+				if b.pos+1 < len(b.buf) && b.buf[b.pos+1] == 0x00 {
+					b.pos += 2
+				} else {
+					//Reached a marker, feed zeros until it is consumed by restart
+					b.marker = true
+					next = 0
+				}
+			} else {
+				b.pos++
+			}
+		}
+		b.acc |= uint64(next) << (56 - b.n)
+		b.n += 8
+	}
+}
+
+func (b *ljpegBits) peek(n uint) uint32 {
+	if b.n < n {
+		b.fill()
+	}
+	return uint32(b.acc >> (64 - n))
+}
+
+func (b *ljpegBits) skip(n uint) {
+	b.acc <<= n
+	b.n -= n
+}
+
+func (b *ljpegBits) read(n uint) uint32 {
+	v := b.peek(n)
+	b.skip(n)
+	return v
+}
+
+//diff decodes a single difference value, ITU T.81 Annex H.1.2.2.
+func (b *ljpegBits) diff(h *ljpegHuffman) (int32, error) {
+	var ssss uint8
+	if e := h.lookup[b.peek(8)]; e != 0 {
+		b.skip(uint(e >> 8))
+		ssss = uint8(e)
+	} else {
+		code := int32(b.read(9))
+		l := 9
+		for code > h.maxcode[l] {
+			code = code<<1 | int32(b.read(1))
+			l++
+			if l > 16 {
+				return 0, errors.New("invalid huffman code")
+			}
+		}
+		ssss = h.vals[h.valptr[l]+int(code-h.mincode[l])]
+	}
+
+	switch {
+	case ssss == 0:
+		return 0, nil
+	case ssss == 16:
+		return 32768, nil
+	case ssss > 16:
+		return 0, errors.New("invalid difference category: " + fmt.Sprint(ssss))
+	}
+
+	v := int32(b.read(uint(ssss)))
+	if v < 1<<(ssss-1) {
+		v -= 1<<ssss - 1
+	}
+	return v, nil
+}
+
+//restart discards the remaining bits of the interval and consumes the RSTn marker.
+func (b *ljpegBits) restart() error {
+	b.acc, b.n = 0, 0
+	b.marker = false
+	for b.pos+1 < len(b.buf) && b.buf[b.pos] == 0xff && b.buf[b.pos+1] == 0xff {
+		b.pos++
+	}
+	if b.pos+1 >= len(b.buf) || b.buf[b.pos] != 0xff || b.buf[b.pos+1] < markerRST0 || b.buf[b.pos+1] > markerRST7 {
+		return errors.New("expected restart marker at offset " + fmt.Sprint(b.pos))
+	}
+	b.pos += 2
+	return nil
+}
@@ -0,0 +1,232 @@
+package arw
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+//encodeLJPEG writes a minimal lossless JPEG with a single huffman table in which every category has a 5 bit code.
+func encodeLJPEG(pix []uint16, width, height, comps int, precision uint, predictor int, restart int) []byte {
+	var out bytes.Buffer
+	out.Write([]byte{0xff, markerSOI})
+
+	//DHT, 17 codes of length 5
+	out.Write([]byte{0xff, markerDHT, 0, 2 + 17 + 17, 0x00})
+	out.Write([]byte{0, 0, 0, 0, 17, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	for i := 0; i < 17; i++ {
+		out.WriteByte(byte(i))
+	}
+
+	out.Write([]byte{0xff, markerSOF3, 0, byte(8 + comps*3), byte(precision), byte(height >> 8), byte(height), byte(width >> 8), byte(width), byte(comps)})
+	for c := 0; c < comps; c++ {
+		out.Write([]byte{byte(c + 1), 0x11, 0})
+	}
+
+	if restart > 0 {
+		out.Write([]byte{0xff, markerDRI, 0, 4, byte(restart >> 8), byte(restart)})
+	}
+
+	out.Write([]byte{0xff, markerSOS, 0, byte(6 + comps*2), byte(comps)})
+	for c := 0; c < comps; c++ {
+		out.Write([]byte{byte(c + 1), 0x00})
+	}
+	out.Write([]byte{byte(predictor), 0, 0})
+
+	var acc uint32
+	var n uint
+	emit := func(v uint32, bits uint) {
+		for i := int(bits) - 1; i >= 0; i-- {
+			acc = acc<<1 | (v>>uint(i))&1
+			n++
+			if n == 8 {
+				out.WriteByte(byte(acc))
+				if byte(acc) == 0xff {
+					out.WriteByte(0)
+				}
+				acc, n = 0, 0
+			}
+		}
+	}
+	flush := func() {
+		for n != 0 {
+			emit(1, 1)
+		}
+	}
+
+	line := width * comps
+	firstLine, start, mcus, rst := true, 0, 0, 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if restart > 0 && mcus == restart {
+				flush()
+				out.Write([]byte{0xff, byte(markerRST0 + rst&7)})
+				rst++
+				mcus = 0
+				firstLine, start = true, x
+			}
+			for c := 0; c < comps; c++ {
+				i := y*line + x*comps + c
+				var pred int32
+				switch {
+				case firstLine && x == start:
+					pred = 1 << (precision - 1)
+				case firstLine:
+					pred = int32(pix[i-comps])
+				case x == 0:
+					pred = int32(pix[i-line])
+				default:
+					ra, rb, rc := int32(pix[i-comps]), int32(pix[i-line]), int32(pix[i-line-comps])
+					switch predictor {
+					case 1:
+						pred = ra
+					case 2:
+						pred = rb
+					case 3:
+						pred = rc
+					case 4:
+						pred = ra + rb - rc
+					case 5:
+						pred = ra + ((rb - rc) >> 1)
+					case 6:
+						pred = rb + ((ra - rc) >> 1)
+					case 7:
+						pred = (ra + rb) >> 1
+					}
+				}
+
+				diff := int32(int16(uint16(int32(pix[i]) - pred)))
+				var ssss uint
+				for a := abs32(diff); a > 0; a >>= 1 {
+					ssss++
+				}
+				emit(uint32(ssss), 5)
+				if ssss > 0 && ssss < 16 {
+					v := diff
+					if v < 0 {
+						v--
+					}
+					emit(uint32(v)&(1<<ssss-1), ssss)
+				}
+			}
+			mcus++
+		}
+		firstLine, start = false, 0
+	}
+	flush()
+	out.Write([]byte{0xff, markerEOI})
+	return out.Bytes()
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestLJPEGRoundTrip(t *testing.T) {
+	const width, height, comps = 37, 11, 4
+	rng := rand.New(rand.NewSource(1))
+
+	for _, precision := range []uint{12, 14} {
+		pix := make([]uint16, width*height*comps)
+		for i := range pix {
+			pix[i] = uint16(rng.Intn(1 << precision))
+		}
+
+		for predictor := 1; predictor <= 7; predictor++ {
+			for _, restart := range []int{0, width} {
+				encoded := encodeLJPEG(pix, width, height, comps, precision, predictor, restart)
+				decoded, err := decodeLJPEG(encoded, len(pix))
+				if err != nil {
+					t.Errorf("precision %v predictor %v restart %v: %v", precision, predictor, restart, err)
+					continue
+				}
+				if decoded.width != width || decoded.height != height || len(decoded.components) != comps {
+					t.Errorf("unexpected frame %vx%vx%v", decoded.width, decoded.height, len(decoded.components))
+					continue
+				}
+				for i := range pix {
+					if decoded.pix[i] != pix[i] {
+						t.Errorf("precision %v predictor %v restart %v: sample %v expected %v, got %v", precision, predictor, restart, i, pix[i], decoded.pix[i])
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLJPEGTooLarge(t *testing.T) {
+	pix := make([]uint16, 4*4*4)
+	encoded := encodeLJPEG(pix, 4, 4, 4, 14, 1, 0)
+	if _, err := decodeLJPEG(encoded, len(pix)-1); err == nil {
+		t.Error("Expected an error for a frame above the limit")
+	}
+
+	//A frame of 65535x65535 must be rejected on the size of its data, not allocated
+	sof := bytes.Index(encoded, []byte{0xff, markerSOF3})
+	copy(encoded[sof+5:], []byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := decodeLJPEG(encoded, 1<<40); err == nil {
+		t.Error("Expected an error for a frame larger than its data")
+	}
+}
+
+func TestLJPEGOversubscribedTable(t *testing.T) {
+	//Three codes of a single bit do not fit
+	dht := make([]byte, 17+3)
+	dht[1] = 3
+	var j ljpeg
+	if err := j.parseDHT(dht); err == nil {
+		t.Error("Expected an error for an oversubscribed table")
+	}
+}
+
+func TestReadCRAWLosslessTiles(t *testing.T) {
+	const width, height, tileSize = 20, 12, 16
+	rng := rand.New(rand.NewSource(2))
+
+	cfa := make([]uint16, width*height)
+	for i := range cfa {
+		cfa[i] = uint16(rng.Intn(1 << 14))
+	}
+
+	var file bytes.Buffer
+	var rw rawDetails
+	rw.width, rw.height, rw.bitDepth = width, height, 14
+	rw.tileWidth, rw.tileHeight = tileSize, tileSize
+
+	for top := 0; top < height; top += tileSize {
+		for left := 0; left < width; left += tileSize {
+			pix := make([]uint16, tileSize*tileSize)
+			for y := 0; y < tileSize/2; y++ {
+				for x := 0; x < tileSize/2; x++ {
+					for c := 0; c < 4; c++ {
+						cx, cy := left+x*2+(c&1), top+y*2+(c>>1)
+						if cx < width && cy < height {
+							pix[(y*tileSize/2+x)*4+c] = cfa[cy*width+cx]
+						}
+					}
+				}
+			}
+			tile := encodeLJPEG(pix, tileSize/2, tileSize/2, 4, 14, 1, 0)
+			rw.tileOffsets = append(rw.tileOffsets, uint32(file.Len()))
+			rw.tileLengths = append(rw.tileLengths, uint32(len(tile)))
+			file.Write(tile)
+		}
+	}
+
+	data, err := unpackLossless(io.NewSectionReader(bytes.NewReader(file.Bytes()), 0, int64(file.Len())), rw, 3)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i := range cfa {
+		if data[i] != cfa[i] {
+			t.Errorf("pixel (%v,%v): expected %v, got %v", i%width, i/width, cfa[i], data[i])
+			break
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package arw
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+//sonyMakerNoteHeaders are the signatures Sony writes in front of the makernote IFD, ARW files usually start with the IFD itself.
+var sonyMakerNoteHeaders = []string{
+	"SONY DSC \x00\x00\x00",
+	"SONY CAM \x00\x00\x00",
+	"SONY MOBILE\x00",
+	"VHAB     \x00\x00\x00",
+}
+
+//Makernotes with these signatures are not IFDs.
+var unsupportedMakerNotes = []string{
+	"SONY PIC\x00",
+	"\x00\x00SONY PIC\x00",
+	"SONY PI\x00",
+	"PREMI\x00",
+}
+
+//ReadMakerNote returns the Sony makernote found through the Exif IFD of IFD0.
+func (f *File) ReadMakerNote() (EXIFIFD, error) {
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return EXIFIFD{}, err
+	}
+	for _, fia := range ifd0.FIA {
+		if fia.Tag != ExifTag {
+			continue
+		}
+		exif, err := f.ExtractMetaData(int64(fia.Offset), 0)
+		if err != nil {
+			return EXIFIFD{}, err
+		}
+		for _, entry := range exif.FIA {
+			if entry.Tag == MakerNote {
+				return f.MakerNote(entry)
+			}
+		}
+	}
+	return EXIFIFD{}, errors.New("no makernote in file")
+}
+
+//MakerNote parses the Sony makernote referenced by a MakerNote entry of the Exif IFD.
+func (f *File) MakerNote(entry IFDFIA) (EXIFIFD, error) {
+	ifd, _, _, err := readMakerNote(f, entry)
+	return ifd, err
+}
+
+//readMakerNote skips a known signature and parses the makernote IFD, also returning the offset of the IFD and the base its value offsets are relative to.
+//Sony stores value offsets relative to the start of the file, makernotes which were moved by other software
+//usually keep offsets relative to the makernote itself. The base which keeps all values inside the makernote is used.
+func readMakerNote(f *File, entry IFDFIA) (EXIFIFD, int64, int64, error) {
+	if entry.Tag != MakerNote {
+		return EXIFIFD{}, 0, 0, errors.New("not a makernote entry: " + fmt.Sprint(entry.Tag))
+	}
+	if entry.Count < 2+12+4 {
+		return EXIFIFD{}, 0, 0, errors.New("makernote too small for an IFD: " + fmt.Sprint(entry.Count))
+	}
+
+	signature := make([]byte, 12)
+	if _, err := f.r.Seek(int64(entry.Offset), 0); err != nil {
+		return EXIFIFD{}, 0, 0, err
+	}
+	if _, err := io.ReadFull(f.r, signature); err != nil {
+		return EXIFIFD{}, 0, 0, err
+	}
+	for _, header := range unsupportedMakerNotes {
+		if string(signature[:len(header)]) == header {
+			return EXIFIFD{}, 0, 0, errors.New("unsupported makernote format: " + fmt.Sprintf("%q", header))
+		}
+	}
+
+	start, end := int64(entry.Offset), int64(entry.Offset)+int64(entry.Count)
+	offset := start
+	for _, header := range sonyMakerNoteHeaders {
+		if string(signature[:len(header)]) == header {
+			offset += int64(len(header))
+			break
+		}
+	}
+
+	absolute, err := f.ExtractMetaData(offset, 0)
+	if err == nil && valuesWithin(absolute, start, end) {
+		return absolute, offset, 0, nil
+	}
+	relative, rerr := extractMetaData(baseReader{f.r, start}, f.order, offset-start, 0)
+	if rerr == nil && valuesWithin(relative, 0, end-start) {
+		return relative, offset, start, nil
+	}
+	if err != nil {
+		return EXIFIFD{}, 0, 0, err
+	}
+	return absolute, offset, 0, nil
+}
+
+//valuesWithin reports whether all values stored outside of the entries lie in [start, end).
+func valuesWithin(ifd EXIFIFD, start, end int64) bool {
+	for _, fia := range ifd.FIA {
+		size := int64(fia.Type.Len()) * int64(fia.Count)
+		if size <= 4 {
+			continue
+		}
+		if int64(fia.Offset) < start || int64(fia.Offset)+size > end {
+			return false
+		}
+	}
+	return true
+}
+
+//baseReader shifts absolute positions by base, for IFDs storing offsets relative to their own position.
+type baseReader struct {
+	r    io.ReadSeeker
+	base int64
+}
+
+func (b baseReader) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+func (b baseReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == 0 {
+		offset += b.base
+	}
+	pos, err := b.r.Seek(offset, whence)
+	return pos - b.base, err
+}
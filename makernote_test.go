@@ -0,0 +1,59 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+var testMakerNote = []testEntry{
+	{SonyModelID, SHORT, []uint16{358}},
+	{LensSpec, BYTE, []byte{0, 0x24, 0, 0x70, 0x28, 0x28, 0, 0}},
+	{CreativeStyle, ASCII, []byte("Standard\x00")},
+}
+
+func TestReadMakerNote(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for _, header := range []string{"", "SONY DSC \x00\x00\x00"} {
+			for _, relative := range []bool{false, true} {
+				f, err := NewFile(bytes.NewReader(buildMakerNoteTIFF(order, header, relative, testMakerNote)))
+				if err != nil {
+					t.Error(err)
+					continue
+				}
+				note, err := f.ReadMakerNote()
+				if err != nil {
+					t.Errorf("%v %q relative %v: %v", order, header, relative, err)
+					continue
+				}
+				if len(note.FIA) != len(testMakerNote) {
+					t.Errorf("%v %q relative %v: expected %v entries, got %v", order, header, relative, len(testMakerNote), len(note.FIA))
+					continue
+				}
+				if (*note.FIAvals[0].short)[0] != 358 || !bytes.Equal(*note.FIAvals[1].ascii, testMakerNote[1].values.([]byte)) || string(*note.FIAvals[2].ascii) != "Standard\x00" {
+					t.Errorf("%v %q relative %v: unexpected values %v", order, header, relative, note)
+				}
+			}
+		}
+	}
+}
+
+func TestReadMakerNoteUnsupported(t *testing.T) {
+	doc := buildMakerNoteTIFF(binary.LittleEndian, "SONY PI\x00\x00\x00\x00\x00", false, testMakerNote)
+	f, err := NewFile(bytes.NewReader(doc))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := f.ReadMakerNote(); err == nil {
+		t.Error("Expected an error for a makernote which is not an IFD")
+	}
+
+	tree, err := f.ReadTree()
+	if err != nil {
+		t.Error(err)
+	}
+	if tree.Find("IFD0/Exif/MakerNote") != nil {
+		t.Error("Expected the makernote to be left out of the tree")
+	}
+}
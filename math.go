@@ -1,90 +1,119 @@
 package arw
 
 import (
-	"fmt"
-	"github.com/gonum/matrix/mat64"
-	"log"
+	"math"
 )
 
 //Helper function for createToneCurve which generates a Van der Monde matrix.
-func vanDerMonde(a []float64, degree int) *mat64.Dense {
-	x := mat64.NewDense(len(a), degree+1, nil)
+func vanDerMonde(a [6]float64) (x [6][6]float64) {
 	for i := range a {
-		for j, p := 0, 1.; j <= degree; j, p = j+1, p*a[i] {
-			x.Set(i, j, p)
+		for j, p := 0, 1.; j < len(x[i]); j, p = j+1, p*a[i] {
+			x[i][j] = p
 		}
 	}
 	return x
 }
 
-//xFactors are coefficients which are used to map incoming data to the Sony provided tone curve.
-var xFactors [6]float64
-var sRGBFactors [3]float64
+//solve6 solves a·c = b by Gaussian elimination with partial pivoting, a has to be regular.
+func solve6(a [6][6]float64, b [6]float64) (c [6]float64) {
+	for col := range a {
+		pivot := col
+		for row := col + 1; row < len(a); row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < len(a); row++ {
+			f := a[row][col] / a[col][col]
+			for k := col; k < len(a); k++ {
+				a[row][k] -= f * a[col][k]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+	for row := len(a) - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < len(a); k++ {
+			sum -= a[row][k] * c[k]
+		}
+		c[row] = sum / a[row][row]
+	}
+	return c
+}
 
+//sRGB applies the sRGB transfer function from IEC 61966-2-1 to a linear value in [0, 1], the result is in 14 bit space.
 func sRGB(x float64) float64 {
-	x2 := sRGBFactors[2] * x * x
-	x1 := sRGBFactors[1] * x
-	x0 := sRGBFactors[0]
-	val := x2 + x1 + x0
-	return val
+	if x <= 0.0031308 {
+		return 12.92 * x * 0x3fff
+	}
+	return (1.055*math.Pow(x, 1/2.4) - 0.055) * 0x3fff
+}
+
+//toneCurve maps linear 14 bit values through the Sony tone curve and the sRGB transfer function.
+//A curve is built per decode so files with different curves can be decoded concurrently.
+type toneCurve struct {
+	//factors are coefficients which are used to map incoming data to the Sony provided tone curve.
+	factors [6]float64
+	lut     [0x4000]uint16
+}
+
+//newToneCurve fits a curve through the normalised points and precomputes the output for every 14 bit input.
+func newToneCurve(points [6]float64) *toneCurve {
+	t := &toneCurve{factors: fitToneCurve(points)}
+	for i := range t.lut {
+		//The fitted tone curve can overshoot slightly at both ends
+		val := t.gamma(float64(i) / 0x3fff)
+		switch {
+		case val < 0:
+			val = 0
+		case val > 1:
+			val = 1
+		}
+		t.lut[i] = uint16(sRGB(val) + 0.5)
+	}
+	return t
 }
 
-func gamma(x float64) float64 {
-	if x > 1 {
-		panic("This shouldn't be happening!" + fmt.Sprint("X=", x))
+//gamma evaluates the fitted curve, inputs outside [0, 1] are clipped.
+func (t *toneCurve) gamma(x float64) float64 {
+	switch {
+	case x < 0:
+		x = 0
+	case x > 1:
+		x = 1
 	}
-	x5 := xFactors[5] * x * x * x * x * x
-	x4 := xFactors[4] * x * x * x * x
-	x3 := xFactors[3] * x * x * x
-	x2 := xFactors[2] * x * x
-	x1 := xFactors[1] * x
-	x0 := xFactors[0] * 1
+	x5 := t.factors[5] * x * x * x * x * x
+	x4 := t.factors[4] * x * x * x * x
+	x3 := t.factors[3] * x * x * x
+	x2 := t.factors[2] * x * x
+	x1 := t.factors[1] * x
+	x0 := t.factors[0] * 1
 	val := x5 + x4 + x3 + x2 + x1 + x0 //The negative signs are already in the numbers
 	return val
 }
 
-//The gamma curve points are in a 14 bit space space where we draw a curve that goes through the points.
-func createToneCurve(curve [6]float64) {
-	x := []float64{0, 0.2, 0.4, 0.6, 0.8, 1}
-	y := []float64{float64(curve[0]), float64(curve[1]), float64(curve[2]), float64(curve[3]), curve[4], curve[5]}
-	const degree = 5
-
-	a := vanDerMonde(x, degree)
-	b := mat64.NewDense(len(y), 1, y)
-	c := mat64.NewDense(degree+1, 1, nil)
-
-	qr := new(mat64.QR)
-	qr.Factorize(a)
-
-	if err := c.SolveQR(qr, false, b); err != nil {
-		log.Println(err)
+//tone looks up a 14 bit value, values above 14 bits are clipped to white.
+func (t *toneCurve) tone(v uint16) uint16 {
+	if v > 0x3fff {
+		v = 0x3fff
 	}
-
-	xFactors[5] = c.At(5, 0)
-	xFactors[4] = c.At(4, 0)
-	xFactors[3] = c.At(3, 0)
-	xFactors[2] = c.At(2, 0)
-	xFactors[1] = c.At(1, 0)
-	xFactors[0] = c.At(0, 0)
+	return t.lut[v]
 }
 
-func createSRGBCurve() {
-	x := []float64{0, 0.50, 1}
-	y := []float64{0, 0x2fff, 0x3fff}
-	const degree = 2
-
-	a := vanDerMonde(x, degree)
-	b := mat64.NewDense(len(y), 1, y)
-	c := mat64.NewDense(degree+1, 1, nil)
-
-	qr := new(mat64.QR)
-	qr.Factorize(a)
-
-	if err := c.SolveQR(qr, false, b); err != nil {
-		log.Println(err)
+//apply maps every channel of the image through the curve.
+func (t *toneCurve) apply(img *RGB14) {
+	for i := range img.Pix {
+		img.Pix[i].R = t.tone(img.Pix[i].R)
+		img.Pix[i].G = t.tone(img.Pix[i].G)
+		img.Pix[i].B = t.tone(img.Pix[i].B)
 	}
+}
 
-	sRGBFactors[2] = c.At(2, 0)
-	sRGBFactors[1] = c.At(1, 0)
-	sRGBFactors[0] = c.At(0, 0)
+//The gamma curve points are in a 14 bit space space where we draw a curve that goes through the points.
+//Six points fix a polynomial of degree 5 exactly, so the Van der Monde system is square.
+func fitToneCurve(curve [6]float64) (factors [6]float64) {
+	x := [6]float64{0, 0.2, 0.4, 0.6, 0.8, 1}
+	return solve6(vanDerMonde(x), curve)
 }
@@ -2,7 +2,13 @@ package arw
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -55,6 +61,7 @@ func TestMetadata(t *testing.T) {
 
 			t.Log("GPS IFD (GPS Info Tag)")
 			t.Log(gps)
+			t.Logf("%+v\n", parseGPS(gps))
 		}
 
 		if fia.Tag == ExifTag {
@@ -65,21 +72,26 @@ func TestMetadata(t *testing.T) {
 
 			t.Log("Exif IFD (Exif Private Tag)")
 			t.Log(exif)
-			//Just an attempt at understanding these crazy MakerNotes..
-			//for i := range exif.FIA {
-			//	if exif.FIA[i].Tag == MakerNote {
-			//		makernote, err := ExtractMetaData(bytes.NewReader(*exif.FIAvals[i].ascii), 0, 0)
-			//		if err != nil || makernote.Count == 0 {
-			//			t.Error(err)
-			//		}
-			//
-			//		t.Log("Really stupid propietary makernote structure.")
-			//		t.Log(makernote)
-			//		for _,v := range makernote.FIA {
-			//			t.Logf("%+v\n",v)
-			//		}
-			//	}
-			//}
+			for i := range exif.FIA {
+				if exif.FIA[i].Tag == MakerNote {
+					testARW.Seek(0, 0)
+					f, err := NewFile(testARW)
+					if err != nil {
+						t.Error(err)
+						continue
+					}
+					makernote, err := f.MakerNote(exif.FIA[i])
+					if err != nil || makernote.Count == 0 {
+						t.Error(err)
+					}
+
+					t.Log("Sony makernote")
+					t.Log(makernote)
+					for _, v := range makernote.FIA {
+						t.Logf("%+v\n", v)
+					}
+				}
+			}
 		}
 
 		if fia.Tag == DNGPrivateData {
@@ -97,7 +109,7 @@ func TestMetadata(t *testing.T) {
 
 			var sr2offset uint32
 			var sr2length uint32
-			var sr2key [4]byte
+			var sr2key uint32
 
 			for i := range dng.FIA {
 				if dng.FIA[i].Tag == IDC_IFD {
@@ -122,21 +134,20 @@ func TestMetadata(t *testing.T) {
 					sr2length = dng.FIA[i].Offset
 				}
 				if dng.FIA[i].Tag == SR2SubIFDKey {
-					key := dng.FIA[i].Offset*0x0edd + 1
-					sr2key[3] = byte((key >> 24) & 0xff)
-					sr2key[2] = byte((key >> 16) & 0xff)
-					sr2key[1] = byte((key >> 8) & 0xff)
-					sr2key[0] = byte((key) & 0xff)
+					sr2key = dng.FIA[i].Offset
 				}
 			}
-			buf := DecryptSR2(testARW, sr2offset, sr2length)
-			br := bytes.NewReader(buf)
+			buf, err := DecryptSR2Key(testARW, sr2offset, sr2length, sr2key)
+			if err != nil {
+				t.Error(err)
+			}
+			br := io.NewSectionReader(sr2Reader{buf, int64(sr2offset)}, 0, int64(sr2offset)+int64(len(buf)))
 
-			sr2, err := ExtractMetaData(br, 0, 0)
+			sr2, err := ExtractMetaData(br, int64(sr2offset), 0)
 			if err != nil {
 				t.Error(err)
 			}
-			t.Logf("SR2len: %v SR2off: %v SR2key: %v\n", sr2length, sr2offset, sr2key)
+			t.Logf("SR2len: %v SR2off: %v SR2key: %#x\n", sr2length, sr2offset, sr2key)
 			t.Log(sr2)
 
 			for _, v := range sr2.FIA {
@@ -171,7 +182,7 @@ func TestNestedHeader(t *testing.T) {
 
 	var sr2offset uint32
 	var sr2length uint32
-	var sr2key [4]byte
+	var sr2key uint32
 	for i := range meta.FIA {
 		if meta.FIA[i].Tag == SR2SubIFDOffset {
 			offset := meta.FIA[i].Offset
@@ -181,20 +192,19 @@ func TestNestedHeader(t *testing.T) {
 			sr2length = meta.FIA[i].Offset
 		}
 		if meta.FIA[i].Tag == SR2SubIFDKey {
-			key := meta.FIA[i].Offset*0x0edd + 1
-			sr2key[3] = byte((key >> 24) & 0xff)
-			sr2key[2] = byte((key >> 16) & 0xff)
-			sr2key[1] = byte((key >> 8) & 0xff)
-			sr2key[0] = byte((key) & 0xff)
+			sr2key = meta.FIA[i].Offset
 		}
 	}
 
-	t.Logf("SR2len: %v SR2off: %v SR2key: %v\n", sr2length, sr2offset, sr2key)
+	t.Logf("SR2len: %v SR2off: %v SR2key: %#x\n", sr2length, sr2offset, sr2key)
 
-	buf := DecryptSR2(testARW, sr2offset, sr2length)
-	br := bytes.NewReader(buf)
+	buf, err := DecryptSR2Key(testARW, sr2offset, sr2length, sr2key)
+	if err != nil {
+		t.Error(err)
+	}
+	br := io.NewSectionReader(sr2Reader{buf, int64(sr2offset)}, 0, int64(sr2offset)+int64(len(buf)))
 
-	meta, err = ExtractMetaData(br, 0, 0)
+	meta, err = ExtractMetaData(br, int64(sr2offset), 0)
 	if err != nil {
 		t.Error(err)
 	}
@@ -204,3 +214,206 @@ func TestNestedHeader(t *testing.T) {
 		t.Logf("%+v\n", v)
 	}
 }
+
+func TestFileByteOrder(t *testing.T) {
+	entries := []testEntry{
+		{ImageWidth, SHORT, []uint16{6000}},
+		{BitsPerSample, SHORT, []uint16{12, 14}},
+		{StripOffsets, LONG, []uint32{123456}},
+		{WB_RGGBLevels, SSHORT, []int16{2600, 1024, 1024, -5}},
+		{Make, ASCII, []byte("SONY\x00")},
+	}
+
+	check := func(order binary.ByteOrder) error {
+		f, err := NewFile(bytes.NewReader(buildTIFF(order, testBlock{entries: entries})))
+		if err != nil {
+			return err
+		}
+		if f.ByteOrder() != order {
+			return errors.New("unexpected byte order " + fmt.Sprint(f.ByteOrder()))
+		}
+		meta, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+		if err != nil {
+			return err
+		}
+		if len(meta.FIAvals) != len(entries) {
+			return errors.New("unexpected entry count " + fmt.Sprint(len(meta.FIAvals)))
+		}
+		got := []interface{}{*meta.FIAvals[0].short, *meta.FIAvals[1].short, *meta.FIAvals[2].long, *meta.FIAvals[3].sshort, *meta.FIAvals[4].ascii}
+		for i := range entries {
+			if !reflect.DeepEqual(got[i], entries[i].values) {
+				return errors.New(order.String() + " " + entries[i].tag.String() + ": expected " + fmt.Sprint(entries[i].values) + ", got " + fmt.Sprint(got[i]))
+			}
+		}
+		return nil
+	}
+
+	//Parse both orders concurrently, a shared byte order would make one of them fail
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			wg.Add(1)
+			go func(order binary.ByteOrder) {
+				defer wg.Done()
+				if err := check(order); err != nil {
+					errs <- err
+				}
+			}(order)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestExtractMetaDataBounds(t *testing.T) {
+	valid := buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{
+		{BitsPerSample, SHORT, []uint16{12, 14}},
+		{WB_RGGBLevels, SSHORT, []int16{2600, 1024, 1024, -5}},
+	}})
+
+	corrupt := func(fn func(buf []byte)) []byte {
+		buf := append([]byte(nil), valid...)
+		fn(buf)
+		return buf
+	}
+	cases := map[string][]byte{
+		"offset past end":   corrupt(func(buf []byte) { binary.LittleEndian.PutUint32(buf[4:], 1<<30) }),
+		"count past end":    corrupt(func(buf []byte) { binary.LittleEndian.PutUint16(buf[8:], 1000) }),
+		"too many entries":  corrupt(func(buf []byte) { binary.LittleEndian.PutUint16(buf[8:], 0xffff) }),
+		"values past end":   corrupt(func(buf []byte) { binary.LittleEndian.PutUint32(buf[10+12+8:], uint32(len(buf)-4)) }),
+		"huge value count":  corrupt(func(buf []byte) { binary.LittleEndian.PutUint32(buf[10+12+4:], 1<<31) }),
+		"truncated entries": valid[:20],
+	}
+	for name, buf := range cases {
+		f, err := NewFile(bytes.NewReader(buf))
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+		if _, err := f.ExtractMetaData(int64(f.Header.Offset), 0); err == nil {
+			t.Error(name + ": expected an error")
+		}
+	}
+
+	//IFD0 pointing to itself as the next IFD
+	loop := corrupt(func(buf []byte) { binary.LittleEndian.PutUint32(buf[10+2*12:], 8) })
+	f, err := NewFile(bytes.NewReader(loop))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if ifds, err := f.IFDs(); err == nil || len(ifds) != 1 {
+		t.Error("Expected the loop to be detected after the first IFD, got:", len(ifds), err)
+	}
+}
+
+func TestSR2Pad(t *testing.T) {
+	//The pad of the placeholder key, as listed by dcraw
+	pad := sr2Pad(0x44332211)
+	if pad[0] != 0xcf7a56ae || pad[1] != 0x0db85837 || pad[126] != 0x5b287a3e || pad[127] != 0 {
+		t.Errorf("Unexpected pad: %#x %#x %#x %#x", pad[0], pad[1], pad[126], pad[127])
+	}
+}
+
+func TestDecryptSR2(t *testing.T) {
+	plain := make([]byte, 1027)
+	for i := range plain {
+		plain[i] = byte(i * 7)
+	}
+	encrypted := append([]byte(nil), plain...)
+	decryptSR2(encrypted[16:], 0x44332211)
+	if !bytes.Equal(encrypted[1024:], plain[1024:]) {
+		t.Error("Expected the trailing bytes to be left unencrypted")
+	}
+
+	//Decrypting twice must give the same result, the pad may not be shared between calls
+	for n := 0; n < 2; n++ {
+		buf, err := DecryptSR2Key(bytes.NewReader(encrypted), 16, uint32(len(plain)-16), sr2PlaceholderKey)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !bytes.Equal(buf, plain[16:]) {
+			t.Error("Decryption", n, "does not match the plain text")
+		}
+	}
+
+	//The deprecated form uses the placeholder key and returns nil on errors
+	if buf := DecryptSR2(bytes.NewReader(encrypted), 16, uint32(len(plain)-16)); !bytes.Equal(buf, plain[16:]) {
+		t.Error("Decryption with the placeholder key does not match the plain text")
+	}
+	if buf := DecryptSR2(bytes.NewReader(encrypted), 16, uint32(len(plain))); buf != nil {
+		t.Error("Expected nil for SR2 data past the end of the file")
+	}
+}
+
+func TestExtractMetaDataBigEndian(t *testing.T) {
+	doc := buildTIFF(binary.BigEndian, testBlock{entries: []testEntry{{ImageWidth, SHORT, []uint16{6000}}}})
+	meta, err := ExtractMetaData(bytes.NewReader(doc), 8, 0)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(meta.FIA) != 1 || meta.FIA[0].Tag != ImageWidth || (*meta.FIAvals[0].short)[0] != 6000 {
+		t.Error("Expected the byte order to be taken from the header, got", meta)
+	}
+}
+
+func TestExtractDetailsSR2(t *testing.T) {
+	raw := []testEntry{
+		{ImageWidth, SHORT, []uint16{6000}},
+		{ImageHeight, SHORT, []uint16{4000}},
+		{BitsPerSample, SHORT, []uint16{14}},
+		{SonyRawFileType, SHORT, []uint16{uint16(raw12)}},
+		{CFARepeatPatternDim, SHORT, []uint16{2, 2}},
+		{CFAPattern2, BYTE, []byte{1, 0, 2, 1}},
+	}
+	sr2 := []testEntry{
+		{BlackLevel2, SHORT, []uint16{512, 513, 514, 515}},
+		{WB_RGGBLevels, SSHORT, []int16{2600, 1024, 1024, 1800}},
+		{ColorMatrix, SSHORT, []int16{1100, -50, -26, -80, 1180, -76, 10, -300, 1314}},
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		rw, err := extractDetails(bytes.NewReader(buildSR2TIFF(order, raw, sr2)))
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+		if rw.blackLevel != [4]uint16{512, 513, 514, 515} || rw.WhiteBalance != [4]int16{2600, 1024, 1024, 1800} || rw.colorMatrix[8] != 1314 {
+			t.Errorf("%v: unexpected details %+v", order, rw)
+		}
+		//Inline values sit in the high bits of the offset field of big endian files
+		if rw.width != 6000 || rw.height != 4000 || rw.bitDepth != 14 || rw.rawType != raw12 {
+			t.Errorf("%v: unexpected frame %vx%v, %v bits, type %v", order, rw.width, rw.height, rw.bitDepth, rw.rawType)
+		}
+		if rw.cfaPattern != [4]uint8{1, 0, 2, 1} || rw.cfaPatternDim != [2]uint16{2, 2} {
+			t.Errorf("%v: unexpected CFA %v %v", order, rw.cfaPattern, rw.cfaPatternDim)
+		}
+
+		//Values of the raw IFD take precedence
+		withBlack := append([]testEntry{{BlackLevel2, SHORT, []uint16{128, 128, 128, 128}}}, raw...)
+		rw, err = extractDetails(bytes.NewReader(buildSR2TIFF(order, withBlack, sr2)))
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+		if rw.blackLevel != [4]uint16{128, 128, 128, 128} || rw.WhiteBalance != [4]int16{2600, 1024, 1024, 1800} {
+			t.Errorf("%v: unexpected details %+v", order, rw)
+		}
+
+		//An SR2SubIFD running past the end of the file is ignored
+		doc := buildSR2TIFF(order, withBlack, sr2)
+		rw, err = extractDetails(bytes.NewReader(doc[:604]))
+		if err != nil {
+			t.Error(order, "damaged SR2SubIFD failed the file:", err)
+			continue
+		}
+		if rw.width != 6000 || rw.blackLevel != [4]uint16{128, 128, 128, 128} || rw.WhiteBalance != [4]int16{} || rw.colorMatrix != [9]int16{} {
+			t.Errorf("%v: unexpected details %+v", order, rw)
+		}
+	}
+}
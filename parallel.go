@@ -0,0 +1,59 @@
+package arw
+
+import (
+	"runtime"
+	"sync"
+)
+
+//workers returns the number of goroutines a decode may use, opts may be nil.
+func (o *Options) workers() int {
+	if o == nil || o.Workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Workers
+}
+
+//parallelBands splits [min, max) in to one band per worker and calls fn for every band on its own goroutine.
+//It returns once all bands are done, with a single worker fn is called on the calling goroutine.
+func parallelBands(min, max, workers int, fn func(start, end int)) {
+	n := max - min
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		if n > 0 {
+			fn(min, max)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(min+n*i/workers, min+n*(i+1)/workers)
+	}
+	wg.Wait()
+}
+
+//bandDemosaicer is implemented by demosaicers which can fill a band of rows of an existing image.
+//The bands of a frame are independent, so they can be interpolated concurrently.
+type bandDemosaicer interface {
+	demosaicRows(cfa *CFA, img *RGB14, y0, y1 int)
+}
+
+//demosaicParallel splits the frame in to row bands when the demosaicer supports it.
+func demosaicParallel(d Demosaicer, cfa *CFA, workers int) *RGB14 {
+	bd, ok := d.(bandDemosaicer)
+	if !ok {
+		return d.Demosaic(cfa)
+	}
+
+	img := NewRGB14(cfa.Rect)
+	parallelBands(cfa.Rect.Min.Y, cfa.Rect.Max.Y, workers, func(y0, y1 int) {
+		bd.demosaicRows(cfa, img, y0, y1)
+	})
+	return img
+}
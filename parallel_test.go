@@ -0,0 +1,123 @@
+package arw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+//randomCFA fills a frame with noise so every interpolation path is exercised.
+func randomCFA(rng *rand.Rand, width, height int) *CFA {
+	cfa := mosaic(width, height, defaultCFAPattern, func(x, y int) [3]uint16 {
+		return [3]uint16{uint16(rng.Intn(0x4000)), uint16(rng.Intn(0x4000)), uint16(rng.Intn(0x4000))}
+	})
+	return cfa
+}
+
+//randomCRAW generates a frame of valid compressed blocks.
+func randomCRAW(rng *rand.Rand, width, height int) []byte {
+	buf := make([]byte, width*height)
+	rng.Read(buf)
+	for base := 0; base+pixelBlockSize <= len(buf); base += pixelBlockSize {
+		min := uint32(rng.Intn(0x400))
+		max := min + uint32(rng.Intn(0x400))
+		minidx := uint32(rng.Intn(pixelBlockSize))
+		maxidx := (minidx + 1 + uint32(rng.Intn(pixelBlockSize-1))) % pixelBlockSize
+		head := binary.LittleEndian.Uint32(buf[base:])&0xc0000000 | max | min<<11 | maxidx<<22 | minidx<<26
+		binary.LittleEndian.PutUint32(buf[base:], head)
+	}
+	return buf
+}
+
+func TestParallelDemosaic(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	cfa := randomCFA(rng, 301, 283)
+
+	for name, d := range demosaicers {
+		serial := d.Demosaic(cfa)
+		for _, workers := range []int{2, 7} {
+			img := demosaicParallel(d, cfa, workers)
+			for i := range serial.Pix {
+				if img.Pix[i] != serial.Pix[i] {
+					t.Errorf("%v with %v workers: pixel (%v,%v) expected %v, got %v", name, workers, i%img.Stride, i/img.Stride, serial.Pix[i], img.Pix[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestParallelUnpackCRAW(t *testing.T) {
+	const width, height = 96, 37
+	rng := rand.New(rand.NewSource(5))
+	buf := randomCRAW(rng, width, height)
+	rw := rawDetails{width: width, height: height}
+
+	serial, err := unpackCRAW(buf, rw, &Options{Workers: 1})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	parallel, err := unpackCRAW(buf, rw, &Options{Workers: 5})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i := range serial {
+		if parallel[i] != serial[i] {
+			t.Errorf("pixel (%v,%v): expected %v, got %v", i%width, i/width, serial[i], parallel[i])
+			break
+		}
+	}
+}
+
+func benchmarkWorkers(b *testing.B, fn func(opts *Options)) {
+	counts := []int{1, 2, 4}
+	if n := runtime.GOMAXPROCS(0); n > 4 {
+		counts = append(counts, n)
+	}
+	for _, workers := range counts {
+		opts := &Options{Workers: workers}
+		b.Run(fmt.Sprint("workers=", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fn(opts)
+			}
+		})
+	}
+}
+
+func BenchmarkReadRaw14(b *testing.B) {
+	const width, height = 1024, 768
+	rng := rand.New(rand.NewSource(6))
+	buf := make([]byte, width*height*2)
+	rng.Read(buf)
+	rw := rawDetails{width: width, height: height, gammaCurve: [5]uint16{8000, 10400, 12900, 14100, 0x3fff}}
+	rw.WhiteBalance = [4]int16{2400, 1024, 1024, 1600}
+
+	benchmarkWorkers(b, func(opts *Options) {
+		readRaw14(buf, rw, opts)
+	})
+}
+
+func BenchmarkReadCRAW(b *testing.B) {
+	const width, height = 1024, 768
+	rng := rand.New(rand.NewSource(7))
+	buf := randomCRAW(rng, width, height)
+	rw := rawDetails{width: width, height: height, gammaCurve: [5]uint16{8000, 10400, 12900, 14100, 0x3fff}}
+	rw.WhiteBalance = [4]int16{2400, 1024, 1024, 1600}
+
+	benchmarkWorkers(b, func(opts *Options) {
+		readCRAW(buf, rw, opts)
+	})
+}
+
+func BenchmarkDemosaicAHD(b *testing.B) {
+	rng := rand.New(rand.NewSource(8))
+	cfa := randomCFA(rng, 1024, 768)
+
+	benchmarkWorkers(b, func(opts *Options) {
+		demosaicParallel(AHD{}, cfa, opts.workers())
+	})
+}
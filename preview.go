@@ -0,0 +1,166 @@
+package arw
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+)
+
+//Preview is a JPEG embedded in a document.
+type Preview struct {
+	//Path names the IFD holding the preview, such as IFD0, IFD1 or IFD0/Exif/MakerNote.
+	Path   string
+	Offset uint32
+	Length uint32
+	Width  int
+	Height int
+}
+
+//Previews lists every JPEG embedded in the TIFF document read from r.
+func Previews(r io.ReadSeeker) ([]Preview, error) {
+	f, err := NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return f.Previews()
+}
+
+//Previews lists the JPEGs referenced by JPEGInterchangeFormat in any IFD, usually the preview of IFD0 and the thumbnail of IFD1,
+//and the PreviewImage of the makernote. JPEGs which can not be decoded are left out.
+func (f *File) Previews() ([]Preview, error) {
+	tree, err := f.ReadTree()
+	if tree == nil {
+		return nil, err
+	}
+
+	var previews []Preview
+	seen := make(map[uint32]bool)
+	add := func(path string, offset, length uint32) {
+		if offset == 0 || length == 0 || length > maxValueSize || seen[offset] {
+			return
+		}
+		if _, err := f.r.Seek(int64(offset), 0); err != nil {
+			return
+		}
+		cfg, err := jpeg.DecodeConfig(io.LimitReader(f.r, int64(length)))
+		if err != nil {
+			return
+		}
+		seen[offset] = true
+		previews = append(previews, Preview{Path: path, Offset: offset, Length: length, Width: cfg.Width, Height: cfg.Height})
+	}
+
+	tree.Walk(func(n *IFDNode) error {
+		var offset, length uint32
+		for _, fia := range n.IFD.FIA {
+			switch fia.Tag {
+			case JPEGInterchangeFormat:
+				offset = fia.Offset
+			case JPEGInterchangeFormatLength:
+				length = fia.Offset
+			case MakerNote:
+				//The offsets of the makernote may be relative to its start, the tree does not keep that base
+				makernote, _, base, err := readMakerNote(f, fia)
+				if err != nil {
+					continue
+				}
+				for _, entry := range makernote.FIA {
+					if entry.Tag == PreviewImage {
+						add(n.Path+"/MakerNote", uint32(base)+entry.Offset, entry.Count)
+					}
+				}
+			}
+		}
+		add(n.Path, offset, length)
+		return nil
+	})
+	return previews, err
+}
+
+//LargestPreview decodes the embedded JPEG with the most pixels and rotates it as given by the Orientation of IFD0.
+func LargestPreview(r io.ReadSeeker) (image.Image, error) {
+	f, err := NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	previews, err := f.Previews()
+	if len(previews) == 0 {
+		if err == nil {
+			err = errors.New("no embedded JPEG")
+		}
+		return nil, err
+	}
+
+	largest := previews[0]
+	for _, p := range previews[1:] {
+		if p.Width*p.Height > largest.Width*largest.Height {
+			largest = p
+		}
+	}
+	if _, err := f.r.Seek(int64(largest.Offset), 0); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(io.LimitReader(f.r, int64(largest.Length)))
+	if err != nil {
+		return nil, errors.New(largest.Path + ": " + err.Error())
+	}
+
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return nil, err
+	}
+	for i, fia := range ifd0.FIA {
+		if fia.Tag == Orientation && ifd0.FIAvals[i].short != nil && len(*ifd0.FIAvals[i].short) > 0 {
+			return orient(img, (*ifd0.FIAvals[i].short)[0])
+		}
+	}
+	return img, nil
+}
+
+//orient transforms img so that it displays upright for an EXIF orientation.
+func orient(img image.Image, orientation uint16) (image.Image, error) {
+	if orientation < 1 || orientation > 8 {
+		return nil, errors.New("invalid orientation: " + fmt.Sprint(orientation))
+	}
+	if orientation == 1 {
+		return img, nil
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	//source maps a pixel of the upright image to the stored one
+	var source func(x, y int) (int, int)
+	out := image.Rect(0, 0, h, w)
+	switch orientation {
+	case 2:
+		out, source = src.Bounds(), func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3:
+		out, source = src.Bounds(), func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4:
+		out, source = src.Bounds(), func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5:
+		source = func(x, y int) (int, int) { return y, x }
+	case 6:
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7:
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8:
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	dst := image.NewRGBA(out)
+	for y := 0; y < out.Dy(); y++ {
+		row := dst.Pix[y*dst.Stride:]
+		for x := 0; x < out.Dx(); x++ {
+			sx, sy := source(x, y)
+			copy(row[x*4:x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst, nil
+}
@@ -3,6 +3,7 @@ package arw
 import (
 	"bytes"
 	"fmt"
+	"image"
 	"image/jpeg"
 	"os"
 	"testing"
@@ -76,3 +77,65 @@ func TestEmbeddedJPEG(t *testing.T) {
 	out, err := os.Create(fmt.Sprint(time.Now().Unix(), "raw", ".jpg"))
 	out.Write(jpg)
 }
+
+//testJPEG encodes a width by height JPEG with a bright top left corner.
+func testJPEG(t *testing.T, width, height int) []byte {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Pix[y*img.Stride+x] = 0xff
+		}
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestPreviews(t *testing.T) {
+	previews, err := Previews(bytes.NewReader(buildPreviewTIFF(t, 1)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := map[string][2]int{
+		"IFD0":                {64, 32},
+		"IFD1":                {16, 8},
+		"IFD0/Exif/MakerNote": {32, 16},
+	}
+	if len(previews) != len(expected) {
+		t.Error("Unexpected previews:", previews)
+	}
+	for _, p := range previews {
+		if size, ok := expected[p.Path]; !ok || size != [2]int{p.Width, p.Height} {
+			t.Errorf("%v: unexpected %vx%v", p.Path, p.Width, p.Height)
+		}
+	}
+}
+
+func TestLargestPreview(t *testing.T) {
+	for orientation, expected := range map[uint16]struct {
+		width, height int
+		corner        image.Point
+	}{
+		1: {64, 32, image.Pt(0, 0)},
+		3: {64, 32, image.Pt(63, 31)},
+		6: {32, 64, image.Pt(31, 0)},
+		8: {32, 64, image.Pt(0, 63)},
+	} {
+		img, err := LargestPreview(bytes.NewReader(buildPreviewTIFF(t, orientation)))
+		if err != nil {
+			t.Error(orientation, err)
+			continue
+		}
+		if b := img.Bounds(); b.Dx() != expected.width || b.Dy() != expected.height {
+			t.Errorf("orientation %v: expected %vx%v, got %vx%v", orientation, expected.width, expected.height, b.Dx(), b.Dy())
+			continue
+		}
+		//The bright corner of the stored image has to end up in the expected corner
+		if r, _, _, _ := img.At(expected.corner.X, expected.corner.Y).RGBA(); r < 0xc000 {
+			t.Errorf("orientation %v: corner %v is dark", orientation, expected.corner)
+		}
+	}
+}
@@ -1,34 +1,63 @@
 package arw
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"image"
-	"reflect"
-	"unsafe"
+	"io"
+	"sort"
+	"sync"
 )
 
-func process(cur uint32, black uint32, whiteBalance float64) uint32 {
-	if cur <= black {
-		return 0
-	} else {
-		cur -= black
+//rggbSites maps every site of a 2x2 CFA pattern to its index in the RGGB ordered black level and white balance tags.
+//The first green in the pattern is taken as the green on the red line.
+func rggbSites(pattern [4]uint8) [4]int {
+	var sites [4]int
+	greens := 0
+	for i, c := range pattern {
+		switch c {
+		case cfaRed:
+			sites[i] = 0
+		case cfaGreen:
+			sites[i] = 1 + greens
+			greens = 1
+		case cfaBlue:
+			sites[i] = 3
+		}
 	}
-
-	balanced := float64(cur) * whiteBalance
-	balanced /= 0x3fff
-	return uint32(sRGB(gamma(balanced)))
+	return sites
 }
 
-func readCRAW(buf []byte, rw rawDetails) *RGB14 {
-	img := NewRGB14(image.Rect(0, 0, int(rw.width), int(rw.height)))
+//whiteBalance returns the RGGB levels of the mode selected in the options, opts may be nil.
+func (o *Options) whiteBalance(rw rawDetails) [4]int16 {
+	if o == nil {
+		return rw.WhiteBalance
+	}
+	switch o.WhiteBalance {
+	case WBDaylight:
+		if rw.daylight[0] > 0 && rw.daylight[1] > 0 && rw.daylight[3] > 0 {
+			return rw.daylight
+		}
+	case WBUnity:
+		return [4]int16{1, 1, 1, 1}
+	}
+	return rw.WhiteBalance
+}
 
-	var gamma [6]float64
-	gamma[0] = 0
-	gamma[1] = float64(rw.gammaCurve[0])
-	gamma[2] = float64(rw.gammaCurve[1])
-	gamma[3] = float64(rw.gammaCurve[2])
-	gamma[4] = float64(rw.gammaCurve[3])
-	gamma[5] = float64(rw.gammaCurve[4])
-	createToneCurve(gamma)
+//develop subtracts the black level and applies white balance, leaving linear 14 bit data in the CFA.
+func develop(data []uint16, rw rawDetails, workers int) *CFA {
+	pattern := rw.cfaPattern
+	if rw.cfaPatternDim == [2]uint16{} {
+		pattern = defaultCFAPattern
+	}
+	cfa := &CFA{
+		Pix:     data,
+		Stride:  int(rw.width),
+		Rect:    image.Rect(0, 0, int(rw.width), int(rw.height)),
+		Pattern: pattern,
+	}
+	sites := rggbSites(pattern)
 
 	var whiteBalanceRGGB [4]float64
 	var maxBalance int16
@@ -49,88 +78,32 @@ func readCRAW(buf []byte, rw rawDetails) *RGB14 {
 	whiteBalanceRGGB[2] = float64(rw.WhiteBalance[2]) / float64(maxBalance)
 	whiteBalanceRGGB[3] = float64(rw.WhiteBalance[3]) / float64(maxBalance)
 
-	for y := 0; y < img.Rect.Max.Y; y++ {
-		for x := 0; x < img.Rect.Max.X; x += 32 {
-			if y%2 == 0 {
-				base := y*img.Stride + x
-
-				//fmt.Printf("Red block on line: %v\t column: %v\n", y, x)
-				block := readCrawBlock(buf[base : base+pixelBlockSize]) //16 red pixels, inverleaved with following 16 green
-				red := block.Decompress()
-
-				//fmt.Printf("Green block on line: %v\t column: %v\n", y, x+pixelBlockSize)
-				block = readCrawBlock(buf[base+pixelBlockSize : base+pixelBlockSize+pixelBlockSize]) // idem
-				green := block.Decompress()
-
-				for ir := range red {
-					red[ir] = pixel(process(uint32(red[ir]), uint32(rw.blackLevel[0]), whiteBalanceRGGB[0]))
-				}
-
-				for ir := range green {
-					green[ir] = pixel(process(uint32(green[ir]), uint32(rw.blackLevel[1]), whiteBalanceRGGB[1]))
+	parallelBands(0, cfa.Rect.Max.Y, workers, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < cfa.Rect.Max.X; x++ {
+				i := y*cfa.Stride + x
+				site := sites[(y&1)*2+(x&1)]
+				cur := cfa.Pix[i]
+				black := rw.blackLevel[site]
+				if cur <= black {
+					cfa.Pix[i] = 0
+					continue
 				}
-				for i := 0; i < pixelBlockSize; i++ {
-					img.Pix[base+(i*2)].R = uint16(red[i])
-					img.Pix[base+(i*2)+1].G = uint16(green[i])
-				}
-			} else {
-				//fmt.Printf("Green block on line: %v\t column: %v\n", y, x)
-				base := y*img.Stride + x
-
-				block := readCrawBlock(buf[base : base+pixelBlockSize]) //16 red pixels, inverleaved with following 16 green
-				green := block.Decompress()
 
-				//fmt.Printf("Green block on line: %v\t column: %v\n", y, x+pixelBlockSize)
-				block = readCrawBlock(buf[base+pixelBlockSize : base+pixelBlockSize+pixelBlockSize]) // idem
-				blue := block.Decompress()
-
-				for ir := range green {
-					green[ir] = pixel(process(uint32(green[ir]), uint32(rw.blackLevel[2]), whiteBalanceRGGB[2]))
-				}
-
-				for ir := range blue {
-					blue[ir] = pixel(process(uint32(blue[ir]), uint32(rw.blackLevel[3]), whiteBalanceRGGB[3]))
-				}
-				for i := 0; i < pixelBlockSize; i++ {
-					img.Pix[base+(i*2)].G = uint16(green[i])
-					img.Pix[base+(i*2)+1].B = uint16(blue[i])
+				balanced := float64(cur-black) * whiteBalanceRGGB[site]
+				if balanced > 0x3fff {
+					balanced = 0x3fff
 				}
+				cfa.Pix[i] = uint16(balanced)
 			}
 		}
-	}
-	for y := 0; y < img.Rect.Max.Y; y++ {
-		for x := 0; x < img.Rect.Max.X; x++ {
-			img.Pix[y*img.Stride+x].G = img.Pix[y*img.Stride+x+1].G
-			img.Pix[y*img.Stride+x].B = img.Pix[(y+1)*img.Stride+x+1].B
-			x++
-			img.Pix[y*img.Stride+x].R = img.Pix[y*img.Stride+x-1].R
-			img.Pix[y*img.Stride+x].B = img.Pix[(y+1)*img.Stride+x].B
-		}
-		y++
-
-		for x := 0; x < img.Rect.Max.X; x++ {
-			img.Pix[y*img.Stride+x].R = img.Pix[(y-1)*img.Stride+x].R
-			img.Pix[y*img.Stride+x].B = img.Pix[y*img.Stride+x+1].B
-			x++
-			img.Pix[y*img.Stride+x].R = img.Pix[(y-1)*img.Stride+x-1].R
-			img.Pix[y*img.Stride+x].G = img.Pix[y*img.Stride+x-1].G
-		}
-	}
+	})
 
-	return img
+	return cfa
 }
 
-func readRaw14(buf []byte, rw rawDetails) *RGB14 {
-	//Since we are working with 14 it bytes we choose to simply change the slice's header
-	sliceHeader := *(*reflect.SliceHeader)(unsafe.Pointer(&buf))
-	sliceHeader.Len /= 2
-	sliceHeader.Cap /= 2
-	data := *(*[]uint16)(unsafe.Pointer(&sliceHeader))
-
-	img := NewRGB14(image.Rect(0, 0, int(rw.width), int(rw.height)))
-
-	var cur uint32
-
+//sonyToneCurve builds the tone curve from the SonyCurve points of the file.
+func sonyToneCurve(rw rawDetails) *toneCurve {
 	var gamma [6]float64
 	gamma[0] = 0
 	gamma[1] = float64(rw.gammaCurve[0])
@@ -145,71 +118,353 @@ func readRaw14(buf []byte, rw rawDetails) *RGB14 {
 	gamma[4] /= gamma[5]
 	gamma[5] /= gamma[5]
 
-	createToneCurve(gamma)
-	createSRGBCurve()
+	return newToneCurve(gamma)
+}
 
-	var whiteBalanceRGGB [4]float64
-	var maxBalance int16
-	if rw.WhiteBalance[0] > rw.WhiteBalance[1] {
-		maxBalance = rw.WhiteBalance[0]
-	} else {
-		maxBalance = rw.WhiteBalance[1]
+//render turns unpacked 14 bit sensor data in to an image.
+func render(data []uint16, rw rawDetails, opts *Options) (*RGB14, error) {
+	width, height := int(rw.width), int(rw.height)
+	if width == 0 || height == 0 || len(data) < width*height {
+		return nil, errors.New("raw data does not cover the frame: " + fmt.Sprint(len(data), " samples for ", width, "x", height))
 	}
-	if rw.WhiteBalance[2] > maxBalance {
-		maxBalance = rw.WhiteBalance[2]
+
+	var demosaic Demosaicer = Bilinear{}
+	if opts != nil && opts.Demosaic != nil {
+		demosaic = opts.Demosaic
 	}
-	if rw.WhiteBalance[3] > maxBalance {
-		maxBalance = rw.WhiteBalance[3]
+
+	workers := opts.workers()
+	rw.WhiteBalance = opts.whiteBalance(rw)
+
+	img := demosaicParallel(demosaic, develop(data, rw, workers), workers)
+	m := cameraToSRGB(rw.colorMatrix)
+	curve := sonyToneCurve(rw)
+	parallelBands(0, img.Rect.Dy(), workers, func(y0, y1 int) {
+		band := img.rows(y0, y1)
+		applyColorMatrix(band, m)
+		curve.apply(band)
+	})
+	return img, nil
+}
+
+func readCRAW(buf []byte, rw rawDetails, opts *Options) (*RGB14, error) {
+	data, err := unpackCRAW(buf, rw, opts)
+	if err != nil {
+		return nil, err
 	}
+	return render(data, rw, opts)
+}
 
-	whiteBalanceRGGB[0] = float64(rw.WhiteBalance[0]) / float64(maxBalance)
-	whiteBalanceRGGB[1] = float64(rw.WhiteBalance[1]) / float64(maxBalance)
-	whiteBalanceRGGB[2] = float64(rw.WhiteBalance[2]) / float64(maxBalance)
-	whiteBalanceRGGB[3] = float64(rw.WhiteBalance[3]) / float64(maxBalance)
+//unpackCRAW decompresses CRAW data, every 32 pixels of a line are stored as a block of the 16 even pixels followed by a block of the 16 odd pixels.
+//Block pairs do not depend on each other so bands of lines are decompressed concurrently.
+//A damaged block pair fails the decode with a *BlockError, unless opts is lenient in which case it is concealed and reported.
+func unpackCRAW(buf []byte, rw rawDetails, opts *Options) ([]uint16, error) {
+	width, height := int(rw.width), int(rw.height)
+	data := make([]uint16, width*height)
+	order := rw.byteOrder()
+
+	var mu sync.Mutex
+	var damaged []*BlockError
+	parallelBands(0, height, opts.workers(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x+2*pixelBlockSize <= width; x += 2 * pixelBlockSize {
+				base := y*width + x
+				if err := unpackCRAWPair(buf, base, data[base:], order); err != nil {
+					mu.Lock()
+					damaged = append(damaged, &BlockError{int64(rw.offset), int64(base), x, y, err})
+					mu.Unlock()
+				}
+			}
+		}
+	})
+	if len(damaged) == 0 {
+		return data, nil
+	}
+
+	sort.Slice(damaged, func(i, j int) bool { return damaged[i].Offset < damaged[j].Offset })
+	if opts == nil || !opts.Lenient {
+		return nil, damaged[0]
+	}
+	for _, e := range damaged {
+		concealCRAWPair(data, width, height, e.X, e.Y)
+		if opts.Damaged != nil {
+			opts.Damaged(e)
+		}
+	}
+	return data, nil
+}
+
+//unpackCRAWPair decompresses the even and odd block of the 32 pixel group at offset base.
+func unpackCRAWPair(buf []byte, base int, data []uint16, order binary.ByteOrder) error {
+	if base+2*pixelBlockSize > len(buf) {
+		return io.ErrUnexpectedEOF
+	}
+
+	even, err := readCrawBlock(buf[base:base+pixelBlockSize], order).Decompress()
+	if err != nil {
+		return err
+	}
+	odd, err := readCrawBlock(buf[base+pixelBlockSize:base+pixelBlockSize+pixelBlockSize], order).Decompress()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < pixelBlockSize; i++ {
+		data[i*2] = even[i]
+		data[(i*2)+1] = odd[i]
+	}
+	return nil
+}
 
-	for y := 0; y < img.Rect.Max.Y; y++ {
-		for x := 0; x < img.Rect.Max.X; x++ {
-			cur = uint32(data[y*img.Stride+x])
-			cur = process(cur, uint32(rw.blackLevel[0]), whiteBalanceRGGB[0])
-			img.Pix[y*img.Stride+x].R = uint16(cur)
-			x++
+//concealCRAWPair replaces a damaged 32 pixel group with the nearest line of the same colours, two lines up or else two lines down.
+func concealCRAWPair(data []uint16, width, height, x, y int) {
+	src := y - 2
+	if src < 0 {
+		src = y + 2
+	}
+	if src >= height {
+		return
+	}
+	copy(data[y*width+x:y*width+x+2*pixelBlockSize], data[src*width+x:])
+}
 
-			cur = uint32(data[y*img.Stride+x])
-			cur = process(cur, uint32(rw.blackLevel[1]), whiteBalanceRGGB[1])
-			img.Pix[y*img.Stride+x].G = uint16(cur)
+func readRaw14(buf []byte, rw rawDetails, opts *Options) (*RGB14, error) {
+	return render(unpackRaw14(buf, rw, opts.workers()), rw, opts)
+}
+
+//unpackRaw14 reads samples stored as 16 bit words in the byte order of the file.
+func unpackRaw14(buf []byte, rw rawDetails, workers int) []uint16 {
+	order := rw.byteOrder()
+	data := make([]uint16, len(buf)/2)
+	parallelBands(0, len(data), workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			data[i] = order.Uint16(buf[i*2:])
 		}
-		y++
+	})
+	return data
+}
+
+//frameBytes is the size of a strip holding every pixel of the frame, CRAW stores a byte per pixel.
+func frameBytes(rw rawDetails) int64 {
+	pixels := int64(rw.width) * int64(rw.height)
+	switch rw.rawType {
+	case raw14:
+		return pixels * 2
+	case raw12:
+		return (pixels*3 + 1) / 2
+	}
+	return pixels
+}
+
+//readStrip reads the strip of sensor data, which has to cover the frame and lie within the file.
+//Checking before allocating keeps a corrupt header from requesting more memory than the file could fill.
+func readStrip(r *io.SectionReader, rw rawDetails) ([]byte, error) {
+	if int64(rw.offset)+int64(rw.length) > r.Size() {
+		return nil, errors.New("raw strip runs past the end of the file: " + fmt.Sprint(rw.offset, "+", rw.length))
+	}
+	if int64(rw.length) < frameBytes(rw) {
+		return nil, errors.New("raw strip does not cover the frame: " + fmt.Sprint(rw.length, " bytes for ", rw.width, "x", rw.height))
+	}
 
-		for x := 0; x < img.Rect.Max.X; x++ {
-			cur = uint32(data[y*img.Stride+x])
-			cur = process(cur, uint32(rw.blackLevel[2]), whiteBalanceRGGB[2])
-			img.Pix[y*img.Stride+x].G = uint16(cur)
-			x++
+	buf := make([]byte, rw.length)
+	if n, err := r.ReadAt(buf, int64(rw.offset)); n != len(buf) {
+		return nil, err
+	}
+	return buf, nil
+}
+
+//readSensorData returns the unprocessed samples of the frame in the bit depth they were stored with, see sensorBits.
+//CRAW samples are the exception, they are linearised to 14 bits.
+func readSensorData(r *io.SectionReader, rw rawDetails, opts *Options) ([]uint16, error) {
+	if rw.rawType == crawLossless {
+		return unpackLossless(r, rw, opts.workers())
+	}
 
-			cur = uint32(data[y*img.Stride+x])
-			cur = process(cur, uint32(rw.blackLevel[3]), whiteBalanceRGGB[3])
-			img.Pix[y*img.Stride+x].B = uint16(cur)
+	buf, err := readStrip(r, rw)
+	if err != nil {
+		return nil, err
+	}
+	switch rw.rawType {
+	case raw14:
+		return unpackRaw14(buf, rw, opts.workers()), nil
+	case raw12:
+		return unpack12(buf, int(rw.width)*int(rw.height)), nil
+	case craw:
+		data, err := unpackCRAW(buf, rw, opts)
+		if err != nil {
+			return nil, err
 		}
+		linearizeCRAW(data, rw)
+		return data, nil
 	}
+	return nil, errors.New("unsupported raw type: " + fmt.Sprint(rw.rawType))
+}
 
-	for y := 0; y < img.Rect.Max.Y; y++ {
-		for x := 0; x < img.Rect.Max.X; x++ {
-			img.Pix[y*img.Stride+x].G = img.Pix[y*img.Stride+x+1].G
-			img.Pix[y*img.Stride+x].B = img.Pix[(y+1)*img.Stride+x+1].B
-			x++
-			img.Pix[y*img.Stride+x].R = img.Pix[y*img.Stride+x-1].R
-			img.Pix[y*img.Stride+x].B = img.Pix[(y+1)*img.Stride+x].B
+//linearizeCRAW expands the 11 bit CRAW samples to linear 14 bit values in place.
+//The SonyCurve points split the range in to five segments, the step between codes doubles from one segment to the next.
+//Without a SonyCurve the samples are taken to be linear already.
+func linearizeCRAW(data []uint16, rw rawDetails) {
+	if rw.gammaCurve == [5]uint16{} {
+		for i, v := range data {
+			data[i] = clamp14(int32(v) << 3)
 		}
-		y++
-
-		for x := 0; x < img.Rect.Max.X; x++ {
-			img.Pix[y*img.Stride+x].R = img.Pix[(y-1)*img.Stride+x].R
-			img.Pix[y*img.Stride+x].B = img.Pix[y*img.Stride+x+1].B
-			x++
-			img.Pix[y*img.Stride+x].R = img.Pix[(y-1)*img.Stride+x-1].R
-			img.Pix[y*img.Stride+x].G = img.Pix[y*img.Stride+x-1].G
+		return
+	}
+
+	//Codes index the curve at twice their value, the points are stored at 14 bits for a 12 bit curve
+	var points [6]int
+	for i := 0; i < 4; i++ {
+		points[i+1] = int(rw.gammaCurve[i]>>2) & 0xfff
+	}
+	points[5] = 0xfff
+	var curve [0x1000]int32
+	for i := range curve {
+		curve[i] = int32(i)
+	}
+	for i := 0; i < 5; i++ {
+		for j := points[i] + 1; j <= points[i+1]; j++ {
+			curve[j] = curve[j-1] + 1<<uint(i)
 		}
 	}
 
-	return img
+	for i, v := range data {
+		if v > 0x7ff {
+			v = 0x7ff
+		}
+		data[i] = clamp14(curve[v<<1])
+	}
+}
+
+//sensorBits is the bit depth of the samples returned by readSensorData.
+func sensorBits(rw rawDetails) uint16 {
+	switch {
+	case rw.rawType == raw12:
+		return 12
+	case rw.rawType == crawLossless && rw.bitDepth > 0 && rw.bitDepth < 14:
+		return rw.bitDepth
+	}
+	return 14
+}
+
+//readRaw12 unpacks 12 bit samples and scales them up to 14 bits so they can share the raw14 pipeline.
+func readRaw12(buf []byte, rw rawDetails, opts *Options) (*RGB14, error) {
+	data := unpack12(buf, int(rw.width)*int(rw.height))
+	scaleTo14(data, &rw, 12)
+
+	return render(data, rw, opts)
+}
+
+//scaleTo14 shifts samples and black levels of lower bit depths in to the 14 bit space the pipeline works in.
+func scaleTo14(data []uint16, rw *rawDetails, bitDepth uint16) {
+	if bitDepth == 0 || bitDepth >= 14 {
+		return
+	}
+	shift := 14 - bitDepth
+	for i := range data {
+		data[i] <<= shift
+	}
+	for i := range rw.blackLevel {
+		rw.blackLevel[i] <<= shift
+	}
+}
+
+//readCRAWLossless decodes a lossless compressed ARW.
+func readCRAWLossless(r *io.SectionReader, rw rawDetails, opts *Options) (*RGB14, error) {
+	data, err := unpackLossless(r, rw, opts.workers())
+	if err != nil {
+		return nil, err
+	}
+
+	scaleTo14(data, &rw, rw.bitDepth)
+	return render(data, rw, opts)
+}
+
+//unpackLossless decodes the lossless JPEG tiles in to a single frame of sensor data.
+//Every JPEG sample carries four components which make up a 2x2 square of the bayer pattern.
+func unpackLossless(r *io.SectionReader, rw rawDetails, workers int) ([]uint16, error) {
+	width, height := int(rw.width), int(rw.height)
+	tileWidth, tileHeight := int(rw.tileWidth), int(rw.tileHeight)
+	if tileWidth == 0 || tileHeight == 0 || tileWidth > 0xffff || tileHeight > 0xffff {
+		return nil, errors.New("lossless compressed ARW without valid tile dimensions: " + fmt.Sprint(tileWidth, "x", tileHeight))
+	}
+	tilesAcross := (width + tileWidth - 1) / tileWidth
+	tilesDown := (height + tileHeight - 1) / tileHeight
+	if len(rw.tileOffsets) < tilesAcross*tilesDown || len(rw.tileLengths) < len(rw.tileOffsets) {
+		return nil, errors.New("lossless compressed ARW is missing tiles, expected: " + fmt.Sprint(tilesAcross*tilesDown))
+	}
+
+	//Tiles have to lie within the file and every pixel takes at least a bit of it, which bounds the frame allocated below
+	var total int64
+	for t := 0; t < tilesAcross*tilesDown; t++ {
+		if int64(rw.tileOffsets[t])+int64(rw.tileLengths[t]) > r.Size() {
+			return nil, errors.New("tile " + fmt.Sprint(t) + " runs past the end of the file")
+		}
+		total += int64(rw.tileLengths[t])
+	}
+	if int64(width)*int64(height) > total*8 {
+		return nil, errors.New("lossless compressed tiles of " + fmt.Sprint(total) + " bytes can not cover the frame")
+	}
+
+	data := make([]uint16, width*height)
+	errs := make([]error, tilesAcross*tilesDown)
+	parallelBands(0, tilesAcross*tilesDown, workers, func(first, last int) {
+		for t := first; t < last; t++ {
+			errs[t] = unpackLosslessTile(r, rw, data, t, (t%tilesAcross)*tileWidth, (t/tilesAcross)*tileHeight)
+			if errs[t] != nil {
+				return
+			}
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+//unpackLosslessTile decodes tile t and stores it in the frame with its top left corner at left, top.
+func unpackLosslessTile(r io.ReaderAt, rw rawDetails, data []uint16, t, left, top int) error {
+	width, height := int(rw.width), int(rw.height)
+	buf := make([]byte, rw.tileLengths[t])
+	if n, err := r.ReadAt(buf, int64(rw.tileOffsets[t])); n != len(buf) {
+		return err
+	}
+	//Every JPEG sample holds four components covering a 2x2 square of the tile
+	tile, err := decodeLJPEG(buf, int(rw.tileWidth)*int(rw.tileHeight))
+	if err != nil {
+		return fmt.Errorf("tile %v: %v", t, err)
+	}
+	if len(tile.components) != 4 {
+		return errors.New("expected 4 components in lossless tile, got: " + fmt.Sprint(len(tile.components)))
+	}
+
+	for jy := 0; jy < tile.height; jy++ {
+		for jx := 0; jx < tile.width; jx++ {
+			sample := tile.pix[(jy*tile.width+jx)*4:]
+			for c := 0; c < 4; c++ {
+				x, y := left+jx*2+(c&1), top+jy*2+(c>>1)
+				if x < width && y < height {
+					data[y*width+x] = sample[c]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//unpack12 unpacks little endian packed 12 bit samples, two samples are stored in every three bytes.
+func unpack12(buf []byte, count int) []uint16 {
+	data := make([]uint16, count)
+	i, j := 0, 0
+	for ; i+1 < count && j+2 < len(buf); i, j = i+2, j+3 {
+		data[i] = uint16(buf[j]) | uint16(buf[j+1]&0x0f)<<8
+		data[i+1] = uint16(buf[j+1])>>4 | uint16(buf[j+2])<<4
+	}
+	//An odd count ends with the first half of a pair, held by two bytes
+	if i+1 == count && j+1 < len(buf) {
+		data[i] = uint16(buf[j]) | uint16(buf[j+1]&0x0f)<<8
+	}
+	return data
 }
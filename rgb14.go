@@ -54,6 +54,11 @@ func (c pixel16) RGBA() (uint32, uint32, uint32, uint32) {
 	//return uint32(c.R), uint32(c.G), uint32(c.B), 0xffff
 }
 
+//rows returns the rows [y0, y1) relative to the top of the image, sharing its pixels.
+func (r *RGB14) rows(y0, y1 int) *RGB14 {
+	return &RGB14{r.Pix[y0*r.Stride : y1*r.Stride], r.Stride, image.Rect(r.Rect.Min.X, r.Rect.Min.Y+y0, r.Rect.Max.X, r.Rect.Min.Y+y1)}
+}
+
 func (r *RGB14) set(x, y int, pixel pixel16) {
 	r.Pix[y*r.Stride+x] = pixel
 }
@@ -0,0 +1,105 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+//Shot is the decoded ShotInfo makernote tag.
+type Shot struct {
+	DateTime    string
+	MetaVersion string
+	//ImageSize is the size of the full image the face positions refer to.
+	ImageSize image.Point
+	//Faces are the detected faces in the coordinates of the full sized, unrotated image.
+	Faces []image.Rectangle
+}
+
+//ShotInfo decodes the ShotInfo tag of the Sony makernote.
+func (f *File) ShotInfo() (*Shot, error) {
+	note, err := f.ReadMakerNote()
+	if err != nil {
+		return nil, err
+	}
+	for i, fia := range note.FIA {
+		if fia.Tag == ShotInfo && note.FIAvals[i].ascii != nil {
+			return parseShotInfo(*note.FIAvals[i].ascii)
+		}
+	}
+	return nil, errors.New("no ShotInfo in makernote")
+}
+
+//parseShotInfo decodes the tag, which carries its own byte order marker.
+//The face information layout changed between MetaVersions. The tag records the offset and length of its face information,
+//so the layout is picked from those as in the conditions of FaceInfo1 and FaceInfo2 in ExifTool. Keying on MetaVersion would
+//fail for every version not listed here, while the offset and length also hold for them.
+func parseShotInfo(data []byte) (*Shot, error) {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte("II")):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte("MM")):
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("ShotInfo without byte order marker")
+	}
+
+	var tags ShotInfoTags
+	if err := binary.Read(bytes.NewReader(data), order, &tags); err != nil {
+		return nil, err
+	}
+	info := &Shot{
+		DateTime:    strings.TrimRight(string(tags.SonyDateTime[:]), "\x00"),
+		MetaVersion: strings.TrimRight(string(tags.MetaVersion[:]), "\x00"),
+		ImageSize:   image.Pt(int(tags.SonyImageWidth), int(tags.SonyImageHeight)),
+	}
+	if tags.FacesDetected == 0 {
+		return info, nil
+	}
+
+	var positions [8][4]uint16
+	//Faces which were not detected may be left out of the tag, pad it to the full layout
+	faceInfo := make([]byte, binary.Size(FaceInfo2{}))
+	if int(tags.FaceInfoOffset) < len(data) {
+		copy(faceInfo, data[tags.FaceInfoOffset:])
+	}
+	switch {
+	case tags.FaceInfoOffset == 0x48 && tags.FaceInfoLength == 0x20:
+		var faces FaceInfo1
+		binary.Read(bytes.NewReader(faceInfo), order, &faces)
+		positions = [8][4]uint16{faces.Face1Position, faces.Face2Position, faces.Face3Position, faces.Face4Position, faces.Face5Position, faces.Face6Position, faces.Face7Position, faces.Face8Position}
+	case tags.FaceInfoOffset == 0x5e && tags.FaceInfoLength == 0x25:
+		var faces FaceInfo2
+		binary.Read(bytes.NewReader(faceInfo), order, &faces)
+		positions = [8][4]uint16{faces.Face1Position, faces.Face2Position, faces.Face3Position, faces.Face4Position, faces.Face5Position, faces.Face6Position, faces.Face7Position, faces.Face8Position}
+	default:
+		return info, errors.New("unknown face information layout: " + fmt.Sprintf("offset %#x length %#x, MetaVersion %q", tags.FaceInfoOffset, tags.FaceInfoLength, info.MetaVersion))
+	}
+
+	for i := 0; i < int(tags.FacesDetected) && i < len(positions); i++ {
+		top, left, height, width := int(positions[i][0]), int(positions[i][1]), int(positions[i][2]), int(positions[i][3])
+		info.Faces = append(info.Faces, image.Rect(left, top, left+width, top+height))
+	}
+	return info, nil
+}
+
+//FacesIn scales the face rectangles to an image with the given bounds, such as a decoded raw or a preview.
+func (s *Shot) FacesIn(bounds image.Rectangle) []image.Rectangle {
+	if s.ImageSize.X == 0 || s.ImageSize.Y == 0 {
+		return nil
+	}
+	scaled := make([]image.Rectangle, len(s.Faces))
+	for i, face := range s.Faces {
+		scaled[i] = image.Rect(
+			bounds.Min.X+face.Min.X*bounds.Dx()/s.ImageSize.X,
+			bounds.Min.Y+face.Min.Y*bounds.Dy()/s.ImageSize.Y,
+			bounds.Min.X+face.Max.X*bounds.Dx()/s.ImageSize.X,
+			bounds.Min.Y+face.Max.Y*bounds.Dy()/s.ImageSize.Y,
+		)
+	}
+	return scaled
+}
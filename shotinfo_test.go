@@ -0,0 +1,103 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+//encodeShotInfo writes a ShotInfo tag of the MetaVersion with the face layout found at faceOffset.
+func encodeShotInfo(order binary.ByteOrder, version string, faceOffset uint16, faces [][4]uint16) []byte {
+	tags := ShotInfoTags{
+		FaceInfoOffset:  faceOffset,
+		SonyImageHeight: 4000,
+		SonyImageWidth:  6000,
+		FacesDetected:   uint16(len(faces)),
+	}
+	copy(tags.SonyDateTime[:], "2018:01:02 03:04:05\x00")
+	copy(tags.MetaVersion[:], version)
+	if order == binary.BigEndian {
+		copy(tags.ByteOrder[:], "MM")
+	} else {
+		copy(tags.ByteOrder[:], "II")
+	}
+
+	var positions [8][4]uint16
+	copy(positions[:], faces)
+	var faceInfo interface{}
+	switch faceOffset {
+	case 0x48:
+		tags.FaceInfoLength = 0x20
+		faceInfo = &FaceInfo1{FacesDetected: uint16(len(faces)), Face1Position: positions[0], Face2Position: positions[1], Face3Position: positions[2]}
+	default:
+		tags.FaceInfoLength = 0x25
+		faceInfo = &FaceInfo2{FacesDetected: uint16(len(faces)), Face1Position: positions[0], Face2Position: positions[1], Face3Position: positions[2]}
+	}
+
+	var out bytes.Buffer
+	binary.Write(&out, order, &tags)
+	out.Write(make([]byte, int(faceOffset)-out.Len()))
+	binary.Write(&out, order, faceInfo)
+	return out.Bytes()
+}
+
+func TestShotInfo(t *testing.T) {
+	faces := [][4]uint16{{100, 200, 300, 400}, {1000, 2000, 50, 60}}
+	expected := []image.Rectangle{image.Rect(200, 100, 600, 400), image.Rect(2000, 1000, 2060, 1050)}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		//MetaVersions as written by cameras with either layout
+		for _, layout := range []struct {
+			version    string
+			faceOffset uint16
+		}{{"DC6303320222000", 0x48}, {"DC7303320222000", 0x5e}} {
+			faceOffset := layout.faceOffset
+			note := []testEntry{{ShotInfo, UNDEFINED, encodeShotInfo(order, layout.version, faceOffset, faces)}}
+			f, err := NewFile(bytes.NewReader(buildMakerNoteTIFF(order, "", false, note)))
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			shot, err := f.ShotInfo()
+			if err != nil {
+				t.Errorf("%v %#x: %v", order, faceOffset, err)
+				continue
+			}
+			if shot.DateTime != "2018:01:02 03:04:05" || shot.MetaVersion != layout.version || shot.ImageSize != image.Pt(6000, 4000) {
+				t.Errorf("%v %#x: unexpected shot info %+v", order, faceOffset, shot)
+			}
+			if len(shot.Faces) != len(expected) {
+				t.Errorf("%v %#x: expected %v, got %v", order, faceOffset, expected, shot.Faces)
+				continue
+			}
+			for i := range expected {
+				if shot.Faces[i] != expected[i] {
+					t.Errorf("%v %#x: expected %v, got %v", order, faceOffset, expected, shot.Faces)
+				}
+			}
+
+			half := shot.FacesIn(image.Rect(0, 0, 3000, 2000))
+			if half[0] != image.Rect(100, 50, 300, 200) {
+				t.Errorf("Unexpected scaled face: %v", half[0])
+			}
+		}
+	}
+}
+
+func TestShotInfoUnknownLayout(t *testing.T) {
+	data := encodeShotInfo(binary.LittleEndian, "DC6303320222000", 0x48, [][4]uint16{{1, 2, 3, 4}})
+	binary.LittleEndian.PutUint16(data[0x32:], 0x30)
+	if _, err := parseShotInfo(data); err == nil {
+		t.Error("Expected an error for an unknown face layout")
+	}
+}
+
+func TestShotInfoUnknownVersion(t *testing.T) {
+	//The layout follows the offset and length of the face information, not the version
+	data := encodeShotInfo(binary.LittleEndian, "DC9903320222000", 0x5e, [][4]uint16{{1, 2, 3, 4}})
+	shot, err := parseShotInfo(data)
+	if err != nil || len(shot.Faces) != 1 || shot.Faces[0] != image.Rect(2, 1, 6, 4) {
+		t.Error("Unexpected faces:", shot, err)
+	}
+}
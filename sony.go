@@ -0,0 +1,248 @@
+package arw
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+//Sony enciphers several makernote tags by substituting every byte b below 249 with b*b*b % 249.
+var encipherTable, decipherTable = buildCipherTables()
+
+func buildCipherTables() (encipher, decipher [256]byte) {
+	for b := 0; b < 256; b++ {
+		c := b
+		if b < 249 {
+			c = b * b * b % 249
+		}
+		encipher[b] = byte(c)
+		decipher[c] = byte(b)
+	}
+	return encipher, decipher
+}
+
+//Decipher returns the plain data of an enciphered makernote tag such as Tag9400, Tag9050 or Tag2010.
+func Decipher(data []byte) []byte {
+	plain := make([]byte, len(data))
+	for i, b := range data {
+		plain[i] = decipherTable[b]
+	}
+	return plain
+}
+
+//Encipher is the inverse of Decipher.
+func Encipher(data []byte) []byte {
+	enciphered := make([]byte, len(data))
+	for i, b := range data {
+		enciphered[i] = encipherTable[b]
+	}
+	return enciphered
+}
+
+//LensMount is the mount of the lens used for a shot.
+type LensMount uint8
+
+const (
+	MountUnknown LensMount = iota
+	MountA
+	MountE
+)
+
+func (m LensMount) String() string {
+	switch m {
+	case MountA:
+		return "A-mount"
+	case MountE:
+		return "E-mount"
+	}
+	return "Unknown"
+}
+
+//LensFormat is the image circle a lens was designed for.
+type LensFormat uint8
+
+const (
+	FormatUnknown LensFormat = iota
+	FormatAPSC
+	FormatFullFrame
+)
+
+func (f LensFormat) String() string {
+	switch f {
+	case FormatAPSC:
+		return "APS-C"
+	case FormatFullFrame:
+		return "Full-frame"
+	}
+	return "Unknown"
+}
+
+//SonyInfo holds the useful fields of the enciphered makernote tags, fields of tags the camera did not write are zero.
+//Offsets follow the Sony tables of ExifTool.
+type SonyInfo struct {
+	ShutterCount           uint32
+	SequenceImageNumber    uint32 //Position of the image in a burst, starting at 1
+	SequenceFileNumber     uint32
+	SequenceLength         uint8
+	ReleaseMode            uint8 //ReleaseMode2, 0 is a single frame and 1 continuous shooting
+	ShotNumberSincePowerUp uint32
+
+	HasBatteryTemperature bool
+	BatteryTemperature    float64 //Degrees Celsius
+	BatteryLevel          uint8   //Percentage
+
+	LensMount  LensMount
+	LensFormat LensFormat
+	LensType   uint16
+	LensType2  uint16
+}
+
+//cipherField is a field at a fixed offset of a deciphered tag, size is 1, 2 or 4 bytes.
+type cipherField struct {
+	offset int
+	size   int
+	set    func(info *SonyInfo, v uint32)
+}
+
+var (
+	setSequenceImage = func(info *SonyInfo, v uint32) { info.SequenceImageNumber = v + 1 }
+	setSequenceFile  = func(info *SonyInfo, v uint32) { info.SequenceFileNumber = v + 1 }
+	setSequenceLen   = func(info *SonyInfo, v uint32) { info.SequenceLength = uint8(v) }
+	setReleaseMode   = func(info *SonyInfo, v uint32) { info.ReleaseMode = uint8(v) }
+	setShotsPowerUp  = func(info *SonyInfo, v uint32) { info.ShotNumberSincePowerUp = v }
+	setShutterCount  = func(info *SonyInfo, v uint32) { info.ShutterCount = v & 0x00ffffff }
+	setLensFormat    = func(info *SonyInfo, v uint32) { info.LensFormat = LensFormat(v) }
+	setLensMount     = func(info *SonyInfo, v uint32) { info.LensMount = LensMount(v) }
+	setLensType      = func(info *SonyInfo, v uint32) { info.LensType = uint16(v) }
+	setLensType2     = func(info *SonyInfo, v uint32) { info.LensType2 = uint16(v) }
+)
+
+//Tag9400 layouts, the first byte of the deciphered data identifies the layout.
+var (
+	tag9400a = []cipherField{{0x08, 4, setSequenceImage}, {0x0c, 4, setSequenceFile}, {0x10, 1, setReleaseMode}, {0x1a, 4, setShotsPowerUp}, {0x22, 1, setSequenceLen}}
+	tag9400b = []cipherField{{0x08, 4, setSequenceImage}, {0x0c, 4, setSequenceFile}, {0x10, 1, setReleaseMode}, {0x16, 4, setShotsPowerUp}, {0x1e, 1, setSequenceLen}}
+	tag9400c = []cipherField{{0x12, 4, setSequenceImage}, {0x16, 4, setSequenceFile}, {0x1a, 1, setSequenceLen}}
+)
+
+//Tag9050 layouts, these can only be told apart by camera model.
+var (
+	tag9050a = []cipherField{{0x32, 4, setShutterCount}, {0x106, 1, setLensFormat}, {0x107, 1, setLensMount}, {0x109, 2, setLensType2}, {0x10b, 2, setLensType}}
+	tag9050b = []cipherField{{0x3a, 4, setShutterCount}, {0x106, 1, setLensFormat}, {0x107, 1, setLensMount}, {0x108, 2, setLensType2}, {0x10a, 2, setLensType}}
+	tag9050c = []cipherField{{0x3a, 4, setShutterCount}}
+)
+
+//All Tag2010 layouts start with the sequence numbers and release mode.
+var tag2010 = []cipherField{{0x00, 4, setSequenceImage}, {0x04, 4, setSequenceFile}, {0x08, 1, setReleaseMode}}
+
+//Models using the first and the latest Tag9050 layout, all others use the second. As in ExifTool only the older ILCA
+//models use the first layout, the ILCA-99M2 uses the second.
+var (
+	tag9050aModels = []string{"SLT-", "NEX-", "ILCA-68", "ILCA-77M2", "ILCE-3000", "ILCE-3500", "ILCE-5000", "ILCE-5100", "ILCE-6000", "ILCE-QX1", "ILCE-7", "ILCE-7R", "ILCE-7S", "ILCE-7M2"}
+	tag9050cModels = []string{"ILCE-1", "ILCE-7M4", "ILCE-7SM3", "ILCE-7RM5", "ILCE-7CR", "ILCE-7CM2", "ILCE-9M3", "ILCE-6700", "ILME-FX3", "ILME-FX30", "ZV-E1"}
+)
+
+//tag9400Layout selects the layout by the first deciphered byte.
+func tag9400Layout(plain []byte) []cipherField {
+	if len(plain) == 0 {
+		return nil
+	}
+	switch plain[0] {
+	case 0x07, 0x09, 0x0a:
+		return tag9400a
+	case 0x0c:
+		return tag9400b
+	case 0x23, 0x24, 0x26, 0x28, 0x31, 0x32, 0x33:
+		return tag9400c
+	}
+	return nil
+}
+
+func tag9050Layout(model string) []cipherField {
+	model = strings.TrimRight(model, "\x00 ")
+	for _, m := range tag9050cModels {
+		if model == m {
+			return tag9050c
+		}
+	}
+	for _, m := range tag9050aModels {
+		if model == m || (strings.HasSuffix(m, "-") && strings.HasPrefix(model, m)) {
+			return tag9050a
+		}
+	}
+	return tag9050b
+}
+
+//decodeFields fills info from the fields which lie within the data.
+func decodeFields(info *SonyInfo, plain []byte, order binary.ByteOrder, fields []cipherField) {
+	for _, field := range fields {
+		if field.offset+field.size > len(plain) {
+			continue
+		}
+		var v uint32
+		switch field.size {
+		case 1:
+			v = uint32(plain[field.offset])
+		case 2:
+			v = uint32(order.Uint16(plain[field.offset:]))
+		case 4:
+			v = order.Uint32(plain[field.offset:])
+		}
+		field.set(info, v)
+	}
+}
+
+//decodeTag9406 reads the battery state, the temperature is stored in degrees Fahrenheit.
+func decodeTag9406(info *SonyInfo, plain []byte) {
+	if len(plain) < 8 {
+		return
+	}
+	info.HasBatteryTemperature = true
+	info.BatteryTemperature = (float64(plain[5]) - 32) / 1.8
+	info.BatteryLevel = plain[7]
+}
+
+//SonyInfo deciphers and decodes Tag9400, Tag9050, Tag9406 and Tag2010 of the makernote.
+//Tag9400 takes precedence over Tag2010 for the sequence fields.
+func (f *File) SonyInfo() (*SonyInfo, error) {
+	note, err := f.ReadMakerNote()
+	if err != nil {
+		return nil, err
+	}
+
+	var model string
+	if ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0); err == nil {
+		for i, fia := range ifd0.FIA {
+			if fia.Tag == Model && ifd0.FIAvals[i].ascii != nil {
+				model = string(*ifd0.FIAvals[i].ascii)
+			}
+		}
+	}
+
+	tags := make(map[IFDtag][]byte)
+	for i, fia := range note.FIA {
+		switch fia.Tag {
+		case Tag9400, Tag9050, Tag9406, Tag2010:
+			if data := note.FIAvals[i].ascii; data != nil {
+				tags[fia.Tag] = Decipher(*data)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("no enciphered tags in makernote")
+	}
+
+	info := &SonyInfo{}
+	if plain, ok := tags[Tag2010]; ok {
+		decodeFields(info, plain, f.order, tag2010)
+	}
+	if plain, ok := tags[Tag9400]; ok {
+		decodeFields(info, plain, f.order, tag9400Layout(plain))
+	}
+	if plain, ok := tags[Tag9050]; ok {
+		decodeFields(info, plain, f.order, tag9050Layout(model))
+	}
+	if plain, ok := tags[Tag9406]; ok {
+		decodeTag9406(info, plain)
+	}
+	return info, nil
+}
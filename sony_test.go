@@ -0,0 +1,111 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDecipher(t *testing.T) {
+	var all [256]byte
+	for i := range all {
+		all[i] = byte(i)
+	}
+	enciphered := Encipher(all[:])
+	if enciphered[2] != 8 || enciphered[3] != 27 || enciphered[249] != 249 || enciphered[255] != 255 {
+		t.Error("Unexpected cipher values:", enciphered[2], enciphered[3], enciphered[249], enciphered[255])
+	}
+	if !bytes.Equal(Decipher(enciphered), all[:]) {
+		t.Error("Decipher is not the inverse of Encipher")
+	}
+}
+
+func TestSonyInfo(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		tag9400 := make([]byte, 0x40)
+		tag9400[0] = 0x0c
+		order.PutUint32(tag9400[0x08:], 2)
+		order.PutUint32(tag9400[0x0c:], 41)
+		tag9400[0x10] = 1
+		order.PutUint32(tag9400[0x16:], 187)
+		tag9400[0x1e] = 5
+
+		tag9050 := make([]byte, 0x120)
+		order.PutUint32(tag9050[0x3a:], 0xab012345)
+		tag9050[0x106] = byte(FormatFullFrame)
+		tag9050[0x107] = byte(MountE)
+		order.PutUint16(tag9050[0x108:], 0x1234)
+		order.PutUint16(tag9050[0x10a:], 32868)
+
+		tag9406 := make([]byte, 0x10)
+		tag9406[5] = 95
+		tag9406[7] = 80
+
+		entries := append([]testEntry{}, testMakerNote...)
+		entries = append(entries,
+			testEntry{Tag9050, UNDEFINED, Encipher(tag9050)},
+			testEntry{Tag9400, UNDEFINED, Encipher(tag9400)},
+			testEntry{Tag9406, UNDEFINED, Encipher(tag9406)},
+		)
+		f, err := NewFile(bytes.NewReader(buildMakerNoteTIFF(order, "SONY DSC \x00\x00\x00", false, entries)))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		info, err := f.SonyInfo()
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+
+		expected := SonyInfo{
+			ShutterCount:           0x012345,
+			SequenceImageNumber:    3,
+			SequenceFileNumber:     42,
+			SequenceLength:         5,
+			ReleaseMode:            1,
+			ShotNumberSincePowerUp: 187,
+			HasBatteryTemperature:  true,
+			BatteryTemperature:     35,
+			BatteryLevel:           80,
+			LensMount:              MountE,
+			LensFormat:             FormatFullFrame,
+			LensType:               32868,
+			LensType2:              0x1234,
+		}
+		if *info != expected {
+			t.Errorf("%v: expected %+v, got %+v", order, expected, *info)
+		}
+	}
+}
+
+func TestSonyInfoMissing(t *testing.T) {
+	f, err := NewFile(bytes.NewReader(buildMakerNoteTIFF(binary.LittleEndian, "", false, testMakerNote)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := f.SonyInfo(); err == nil {
+		t.Error("Expected an error for a makernote without enciphered tags")
+	}
+}
+
+func TestTag9050Layout(t *testing.T) {
+	for model, expected := range map[string][]cipherField{
+		"SLT-A99V\x00":  tag9050a,
+		"ILCE-7\x00":    tag9050a,
+		"ILCE-7M2\x00":  tag9050a,
+		"ILCA-68\x00":   tag9050a,
+		"ILCA-77M2\x00": tag9050a,
+		"ILCA-99M2\x00": tag9050b,
+		"ILCE-7M3\x00":  tag9050b,
+		"ILCE-7RM2":     tag9050b,
+		"ILCE-7RM4":     tag9050b,
+		"ILCE-1\x00":    tag9050c,
+		"":              tag9050b,
+	} {
+		if layout := tag9050Layout(model); &layout[0] != &expected[0] {
+			t.Errorf("%q: unexpected Tag9050 layout", model)
+		}
+	}
+}
@@ -6,7 +6,17 @@ import (
 )
 
 func TestSRGB(t *testing.T) {
-	createSRGBCurve()
+	if sRGB(0) != 0 {
+		t.Error("Expected black to stay black, got:", sRGB(0))
+	}
+	if int(sRGB(1)+0.5) != 0x3fff {
+		t.Errorf("Expected white to map to %#x, got: %v", 0x3fff, sRGB(1))
+	}
+	//IEC 61966-2-1 encodes 18% grey as 0.4614
+	if grey := sRGB(0.18) / 0x3fff; grey < 0.461 || grey > 0.462 {
+		t.Error("Unexpected encoding of 18% grey:", grey)
+	}
+
 	var results []float64
 	for i := 0.00; i <= 1.00; i += 0.01 {
 		//	t.Logf("%.2f:\t%.2f\t%x", i, sRGB(i), int(sRGB(i)))
@@ -19,11 +29,17 @@ func TestSRGB(t *testing.T) {
 }
 
 func TestToneCurve(t *testing.T) {
-	createToneCurve([6]float64{0, 8000, 10400, 12900, 14100, 0x3fff})
+	curve := newToneCurve([6]float64{0, 8000. / 0x3fff, 10400. / 0x3fff, 12900. / 0x3fff, 14100. / 0x3fff, 1})
 	var results []float64
 	for i := 0.00; i <= 1.00; i += 0.01 {
-		//t.Logf("%d:\t%.2f\t%x", i, gamma(float64(i)), int(gamma(float64(i))))
-		results = append(results, gamma(float64(i)))
+		//t.Logf("%d:\t%.2f\t%x", i, curve.gamma(float64(i)), int(curve.gamma(float64(i))))
+		results = append(results, curve.gamma(float64(i)))
+	}
+	if curve.gamma(1.5) != curve.gamma(1) {
+		t.Error("Expected inputs above 1 to be clipped, got:", curve.gamma(1.5))
+	}
+	if curve.tone(0) != 0 || curve.tone(0x3fff) != 0x3fff {
+		t.Error("Expected the curve to keep black and white, got:", curve.tone(0), curve.tone(0x3fff))
 	}
 
 	fmt.Println("# x y")
@@ -31,3 +47,14 @@ func TestToneCurve(t *testing.T) {
 		fmt.Println(0.01*float64(i), v)
 	}
 }
+
+func TestFitToneCurve(t *testing.T) {
+	points := [6]float64{0, 0.3, 0.5, 0.7, 0.85, 1}
+	curve := &toneCurve{factors: fitToneCurve(points)}
+	for i, y := range points {
+		x := float64(i) / 5
+		if got := curve.gamma(x); got < y-1e-9 || got > y+1e-9 {
+			t.Errorf("curve misses point %v: expected %v, got %v", x, y, got)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package arw
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"math"
+)
+
+//TIFFOptions are the parameters used by EncodeTIFF.
+type TIFFOptions struct {
+	//ColorSpace of the written pixels and embedded ICC profile, the image is taken to be sRGB.
+	ColorSpace ColorProfile
+	//Source is the ARW the image was decoded from, its descriptive IFD0 tags, Exif IFD and XMP packet are copied when set.
+	//It needs a Size or Stat method like the reader passed to DecodeWithOptions.
+	Source io.ReaderAt
+}
+
+//tiffRowsPerStrip keeps strips small enough to compress and decompress independently.
+const tiffRowsPerStrip = 64
+
+//EncodeTIFF writes img as a 16 bit per sample RGB TIFF with deflate compression and an ICC profile, opts may be nil.
+//Alpha is not written, translucent pixels keep their colour.
+func EncodeTIFF(w io.Writer, img image.Image, opts *TIFFOptions) error {
+	if opts == nil {
+		opts = &TIFFOptions{}
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return errors.New("empty image")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	var src *File
+	if opts.Source != nil {
+		rs, err := sizedReader(opts.Source)
+		if err != nil {
+			return err
+		}
+		f, err := NewFile(rs)
+		if err != nil {
+			return err
+		}
+		//Copied values keep the byte order of the source
+		src, order = f, f.order
+	}
+	tw := newTIFFWriter(order)
+
+	convert := newColorConverter(opts.ColorSpace)
+	at := rgbAt(img)
+	var offsets, counts []uint32
+	row := make([]byte, width*6)
+	for y0 := 0; y0 < height; y0 += tiffRowsPerStrip {
+		var strip bytes.Buffer
+		z := zlib.NewWriter(&strip)
+		for y := y0; y < y0+tiffRowsPerStrip && y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b := at(bounds.Min.X+x, bounds.Min.Y+y)
+				rgb := convert(r, g, b)
+				for c, v := range rgb {
+					order.PutUint16(row[x*6+c*2:], v)
+				}
+			}
+			if _, err := z.Write(row); err != nil {
+				return err
+			}
+		}
+		if err := z.Close(); err != nil {
+			return err
+		}
+		offsets = append(offsets, tw.write(strip.Bytes()))
+		counts = append(counts, uint32(strip.Len()))
+	}
+
+	values := []entryValues{
+		{NewSubFileType, LONG, []uint32{0}},
+		{ImageWidth, LONG, []uint32{uint32(width)}},
+		{ImageHeight, LONG, []uint32{uint32(height)}},
+		{BitsPerSample, SHORT, []uint16{16, 16, 16}},
+		{Compression, SHORT, []uint16{compressionDeflate}},
+		{PhotometricInterpretation, SHORT, []uint16{photometricRGB}},
+		{StripOffsets, LONG, offsets},
+		{SamplesPerPixel, SHORT, []uint16{3}},
+		{RowsPerStrip, LONG, []uint32{tiffRowsPerStrip}},
+		{StripByteCounts, LONG, counts},
+		{PlanarConfiguration, SHORT, []uint16{1}},
+		{InterColorProfile, UNDEFINED, iccProfile(opts.ColorSpace)},
+	}
+	var copied []tiffEntry
+
+	if src != nil {
+		ifd0, err := src.ExtractMetaData(int64(src.Header.Offset), 0)
+		if err != nil {
+			return err
+		}
+		copied, err = copyEntries(src, ifd0, func(tag IFDtag) bool { return !descriptiveTags[tag] && tag != XMP })
+		if err != nil {
+			return err
+		}
+
+		for _, fia := range ifd0.FIA {
+			if fia.Tag == ExifTag {
+				exif, err := writeExifCopy(tw, src, int64(fia.Offset))
+				if err != nil {
+					return err
+				}
+				values = append(values, entryValues{ExifTag, LONG, []uint32{exif}})
+			}
+		}
+	}
+
+	entries, err := newEntries(order, values)
+	if err != nil {
+		return err
+	}
+	return tw.finish(w, tw.writeIFD(append(entries, copied...), 0))
+}
+
+//rgbAt returns a lookup of the 16 bit RGB samples of img. RGB14 and RGBA64 images are read directly, others through At
+//which allocates for every pixel. The TIFF has no alpha channel, so translucent pixels are un-premultiplied to keep their colour.
+func rgbAt(img image.Image) func(x, y int) (r, g, b uint16) {
+	switch src := img.(type) {
+	case *RGB14:
+		return func(x, y int) (uint16, uint16, uint16) {
+			r, g, b, _ := src.at(x, y).RGBA()
+			return uint16(r), uint16(g), uint16(b)
+		}
+	case *image.RGBA64:
+		return func(x, y int) (uint16, uint16, uint16) {
+			c := src.RGBA64At(x, y)
+			return unpremultiply(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+		}
+	}
+	return func(x, y int) (uint16, uint16, uint16) {
+		return unpremultiply(img.At(x, y).RGBA())
+	}
+}
+
+//unpremultiply divides the alpha premultiplied samples by alpha, fully transparent pixels become black.
+func unpremultiply(r, g, b, a uint32) (uint16, uint16, uint16) {
+	switch a {
+	case 0xffff:
+		return uint16(r), uint16(g), uint16(b)
+	case 0:
+		return 0, 0, 0
+	}
+	var out [3]uint16
+	for c, v := range [3]uint32{r, g, b} {
+		//Samples above alpha are not valid premultiplied colours and clip to white
+		if v >= a {
+			out[c] = 0xffff
+		} else {
+			out[c] = uint16((v*0xffff + a/2) / a)
+		}
+	}
+	return out[0], out[1], out[2]
+}
+
+//newColorConverter returns a conversion of 16 bit sRGB samples in to the colour space.
+func newColorConverter(cs ColorProfile) func(r, g, b uint16) [3]uint16 {
+	if cs == SRGB {
+		return func(r, g, b uint16) [3]uint16 { return [3]uint16{r, g, b} }
+	}
+
+	linear := make([]float64, 0x10000)
+	for i := range linear {
+		linear[i] = srgbLinear(float64(i) / 0xffff)
+	}
+	m := mul3(invert3(cs.colorants()), SRGB.colorants())
+	return func(r, g, b uint16) [3]uint16 {
+		in := [3]float64{linear[r], linear[g], linear[b]}
+		var out [3]uint16
+		for c := range out {
+			v := m[c][0]*in[0] + m[c][1]*in[1] + m[c][2]*in[2]
+			out[c] = uint16(math.Round(cs.encode(v) * 0xffff))
+		}
+		return out
+	}
+}
@@ -0,0 +1,179 @@
+package arw
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+func TestEncodeTIFF(t *testing.T) {
+	img := NewRGB14(image.Rect(0, 0, 7, 70))
+	for i := range img.Pix {
+		img.Pix[i] = pixel16{R: uint16(i * 31 % 0x4000), G: uint16(i * 7 % 0x4000), B: 0x3fff}
+	}
+	source := buildRaw14ARW(t, 16, 8, make([]uint16, 16*8))
+
+	var out bytes.Buffer
+	if err := EncodeTIFF(&out, img, &TIFFOptions{Source: bytes.NewReader(source)}); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	tree, err := ReadTree(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	ifd0 := make(map[IFDtag]FIAval)
+	node := tree.Find("IFD0")
+	for i, fia := range node.IFD.FIA {
+		ifd0[fia.Tag] = node.IFD.FIAvals[i]
+	}
+
+	for tag, expected := range map[IFDtag]string{
+		ImageWidth:    "7",
+		ImageHeight:   "70",
+		BitsPerSample: "16, 16, 16",
+		Compression:   "8",
+		Make:          "SONY\x00",
+		XMP:           fmt.Sprintf("%x", testXMP),
+	} {
+		if got := ifd0[tag].String(); got != expected {
+			t.Errorf("%v: expected %q, got %q", tag, expected, got)
+		}
+	}
+	if exif := tree.Find("IFD0/Exif"); exif == nil || len(exif.IFD.FIA) != 2 {
+		t.Error("Expected the Exif IFD without makernote to be copied:", exif)
+	}
+
+	icc := *ifd0[InterColorProfile].ascii
+	if len(icc) < 128 || string(icc[36:40]) != "acsp" || binary.BigEndian.Uint32(icc) != uint32(len(icc)) {
+		t.Error("Invalid ICC profile header")
+	}
+
+	var pix []byte
+	offsets, counts := *ifd0[StripOffsets].long, *ifd0[StripByteCounts].long
+	if len(offsets) != 2 {
+		t.Error("Expected 2 strips, got:", len(offsets))
+	}
+	for i := range offsets {
+		z, err := zlib.NewReader(bytes.NewReader(out.Bytes()[offsets[i] : offsets[i]+counts[i]]))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		strip, err := io.ReadAll(z)
+		if err != nil {
+			t.Error(err)
+		}
+		pix = append(pix, strip...)
+	}
+	if len(pix) != 7*70*6 {
+		t.Error("Unexpected pixel data length:", len(pix))
+		t.FailNow()
+	}
+	for i, p := range img.Pix {
+		r, g, b, _ := p.RGBA()
+		got := [3]uint32{uint32(binary.LittleEndian.Uint16(pix[i*6:])), uint32(binary.LittleEndian.Uint16(pix[i*6+2:])), uint32(binary.LittleEndian.Uint16(pix[i*6+4:]))}
+		if got != [3]uint32{r, g, b} {
+			t.Errorf("pixel %v: expected %v, got %v", i, [3]uint32{r, g, b}, got)
+			break
+		}
+	}
+
+	//Pixels of decoded images are read without boxing them in a color.Color
+	at := rgbAt(img)
+	if n := testing.AllocsPerRun(100, func() { at(3, 5) }); n != 0 {
+		t.Error("Reading an RGB14 pixel allocates:", n)
+	}
+}
+
+func TestEncodeTIFFAlpha(t *testing.T) {
+	rgba64 := image.NewRGBA64(image.Rect(0, 0, 4, 1))
+	rgba64.SetRGBA64(0, 0, color.RGBA64{0x4000, 0x2000, 0, 0x8000})
+	rgba64.SetRGBA64(1, 0, color.RGBA64{0x1234, 0x5678, 0x9abc, 0xffff})
+	rgba64.SetRGBA64(2, 0, color.RGBA64{})
+	nrgba := image.NewNRGBA(rgba64.Rect)
+	nrgba.SetNRGBA(0, 0, color.NRGBA{0x80, 0x40, 0, 0x80})
+	nrgba.SetNRGBA(1, 0, color.NRGBA{0x12, 0x56, 0x9a, 0xff})
+
+	expected := map[string][][3]uint16{
+		"RGBA64": {{0x7fff, 0x3fff, 0}, {0x1234, 0x5678, 0x9abc}, {0, 0, 0}, {0, 0, 0}},
+		"NRGBA":  {{0x8080, 0x4040, 0}, {0x1212, 0x5656, 0x9a9a}, {0, 0, 0}, {0, 0, 0}},
+	}
+	for name, img := range map[string]image.Image{"RGBA64": rgba64, "NRGBA": nrgba} {
+		var out bytes.Buffer
+		if err := EncodeTIFF(&out, img, nil); err != nil {
+			t.Error(name, err)
+			continue
+		}
+		pix := decodeTIFFStrips(t, out.Bytes())
+		for i, rgb := range expected[name] {
+			got := [3]uint16{binary.LittleEndian.Uint16(pix[i*6:]), binary.LittleEndian.Uint16(pix[i*6+2:]), binary.LittleEndian.Uint16(pix[i*6+4:])}
+			//Un-premultiplying 8 bit colours is off by rounding
+			for c := range got {
+				if d := int(got[c]) - int(rgb[c]); d < -2 || d > 2 {
+					t.Errorf("%v pixel %v: expected %#x, got %#x", name, i, rgb, got)
+					break
+				}
+			}
+		}
+	}
+}
+
+//decodeTIFFStrips returns the inflated pixel data of a TIFF written by EncodeTIFF.
+func decodeTIFFStrips(t *testing.T, doc []byte) []byte {
+	tree, err := ReadTree(bytes.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifd0 := make(map[IFDtag]FIAval)
+	node := tree.Find("IFD0")
+	for i, fia := range node.IFD.FIA {
+		ifd0[fia.Tag] = node.IFD.FIAvals[i]
+	}
+	var pix []byte
+	offsets, counts := *ifd0[StripOffsets].long, *ifd0[StripByteCounts].long
+	for i := range offsets {
+		z, err := zlib.NewReader(bytes.NewReader(doc[offsets[i] : offsets[i]+counts[i]]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		strip, err := io.ReadAll(z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pix = append(pix, strip...)
+	}
+	return pix
+}
+
+func TestColorConversion(t *testing.T) {
+	near := func(a uint16, b float64) bool { return float64(a) > b-0.01*0xffff && float64(a) < b+0.01*0xffff }
+	for _, cs := range []ColorProfile{SRGB, AdobeRGB, ProPhotoRGB} {
+		white := newColorConverter(cs)(0xffff, 0xffff, 0xffff)
+		if !near(white[0], 0xffff) || !near(white[1], 0xffff) || !near(white[2], 0xffff) {
+			t.Errorf("%v: white converts to %v", cs, white)
+		}
+	}
+
+	//sRGB red is (219, 0, 0) in 8 bit Adobe RGB
+	red := newColorConverter(AdobeRGB)(0xffff, 0, 0)
+	if !near(red[0], 219.0/255*0xffff) || !near(red[1], 0) || !near(red[2], 0) {
+		t.Error("sRGB red converts to", red)
+	}
+}
+
+func TestNewEntries(t *testing.T) {
+	entries, err := newEntries(binary.BigEndian, []entryValues{{ImageWidth, SHORT, []uint16{6000}}, {Make, ASCII, "SONY"}})
+	if err != nil || len(entries) != 2 || !bytes.Equal(entries[0].data, []byte{0x17, 0x70}) {
+		t.Error("Unexpected entries:", entries, err)
+	}
+	if _, err := newEntries(binary.BigEndian, []entryValues{{ImageWidth, SHORT, "6000"}}); err == nil {
+		t.Error("Expected a string to fail as SHORT values")
+	}
+}
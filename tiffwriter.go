@@ -0,0 +1,182 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+//Values of the tags written by WriteDNG and EncodeTIFF.
+const (
+	photometricRGB     = 2
+	photometricYCbCr   = 6
+	photometricCFA     = 32803
+	compressionNone    = 1
+	compressionJPEG    = 7
+	compressionDeflate = 8
+	illuminantD65      = 21
+)
+
+//tiffEntry is an IFD entry to be written, data holds its values in the byte order of the document.
+type tiffEntry struct {
+	tag   IFDtag
+	typ   IFDtype
+	count uint32
+	data  []byte
+}
+
+//newEntry encodes values as for Editor.Set.
+func newEntry(order binary.ByteOrder, tag IFDtag, typ IFDtype, values interface{}) (tiffEntry, error) {
+	data, count, err := encodeValues(order, typ, values)
+	if err != nil {
+		return tiffEntry{}, errors.New(tag.String() + ": " + err.Error())
+	}
+	return tiffEntry{tag, typ, count, data}, nil
+}
+
+//entryValues are the values of an entry before encoding.
+type entryValues struct {
+	tag    IFDtag
+	typ    IFDtype
+	values interface{}
+}
+
+//newEntries encodes a list of entries, stopping at the first which can not be encoded.
+func newEntries(order binary.ByteOrder, list []entryValues) ([]tiffEntry, error) {
+	entries := make([]tiffEntry, 0, len(list))
+	for _, v := range list {
+		entry, err := newEntry(order, v.tag, v.typ, v.values)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+//tiffWriter lays out a TIFF document in memory, blocks are appended in the order they are written
+//so data and child IFDs are written before the IFDs pointing to them.
+type tiffWriter struct {
+	order binary.ByteOrder
+	buf   bytes.Buffer
+}
+
+//newTIFFWriter starts a document with a header, the offset of IFD0 is filled in by finish.
+func newTIFFWriter(order binary.ByteOrder) *tiffWriter {
+	w := &tiffWriter{order: order}
+	w.buf.Write(tiffMagic(order))
+	binary.Write(&w.buf, order, uint32(0))
+	return w
+}
+
+func tiffMagic(order binary.ByteOrder) []byte {
+	if order == binary.BigEndian {
+		return []byte("MM\x00*")
+	}
+	return []byte("II*\x00")
+}
+
+//write appends data at a word boundary and returns its offset.
+func (w *tiffWriter) write(data []byte) uint32 {
+	if w.buf.Len()%2 == 1 {
+		w.buf.WriteByte(0)
+	}
+	offset := uint32(w.buf.Len())
+	w.buf.Write(data)
+	return offset
+}
+
+//writeIFD appends an IFD sorted by tag followed by the values which do not fit in its entries and returns its offset.
+func (w *tiffWriter) writeIFD(entries []tiffEntry, next uint32) uint32 {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	offset := w.write(nil)
+	dataOffset := offset + uint32(ifdSize(len(entries)))
+	var ifd, data bytes.Buffer
+	binary.Write(&ifd, w.order, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&ifd, w.order, uint16(e.tag))
+		binary.Write(&ifd, w.order, uint16(e.typ))
+		binary.Write(&ifd, w.order, e.count)
+		if len(e.data) <= 4 {
+			field := make([]byte, 4)
+			copy(field, e.data)
+			ifd.Write(field)
+			continue
+		}
+		if data.Len()%2 == 1 {
+			data.WriteByte(0)
+		}
+		binary.Write(&ifd, w.order, dataOffset+uint32(data.Len()))
+		data.Write(e.data)
+	}
+	binary.Write(&ifd, w.order, next)
+
+	w.buf.Write(ifd.Bytes())
+	w.buf.Write(data.Bytes())
+	return offset
+}
+
+//finish points the header at IFD0 and writes the document to out.
+func (w *tiffWriter) finish(out io.Writer, ifd0 uint32) error {
+	doc := w.buf.Bytes()
+	w.order.PutUint32(doc[4:], ifd0)
+	_, err := out.Write(doc)
+	return err
+}
+
+//copyEntries returns the entries of ifd with their values copied verbatim from the file, entries for which
+//skip returns true and entries of unknown types are left out.
+//The values keep the byte order of the file.
+func copyEntries(f *File, ifd EXIFIFD, skip func(IFDtag) bool) ([]tiffEntry, error) {
+	var entries []tiffEntry
+	for _, fia := range ifd.FIA {
+		if fia.Type.Len() < 0 || skip(fia.Tag) {
+			continue
+		}
+		size := valueSize(fia)
+		data := make([]byte, 4)
+		if size <= 4 {
+			f.order.PutUint32(data, fia.Offset)
+			data = data[:size]
+		} else {
+			data = make([]byte, size)
+			if _, err := f.r.Seek(int64(fia.Offset), 0); err != nil {
+				return nil, err
+			}
+			if _, err := io.ReadFull(f.r, data); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, tiffEntry{fia.Tag, fia.Type, fia.Count, data})
+	}
+	return entries, nil
+}
+
+//descriptiveTags are the entries of IFD0 describing the shot which are copied to exported images.
+var descriptiveTags = map[IFDtag]bool{
+	Make:        true,
+	Model:       true,
+	Orientation: true,
+	DateTime:    true,
+	Artist:      true,
+	Copyright:   true,
+}
+
+//writeExifCopy copies the Exif IFD at offset, leaving out entries which point in to the ARW.
+func writeExifCopy(tw *tiffWriter, f *File, offset int64) (uint32, error) {
+	exif, err := f.ExtractMetaData(offset, 0)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := copyEntries(f, exif, func(tag IFDtag) bool {
+		_, child := childIFDs[tag]
+		return child || offsetTags[tag]
+	})
+	if err != nil {
+		return 0, err
+	}
+	return tw.writeIFD(entries, 0), nil
+}
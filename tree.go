@@ -0,0 +1,183 @@
+package arw
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//IFDNode is a single IFD of a TIFF document together with the IFDs its entries point to.
+type IFDNode struct {
+	//Path names the IFD by the way it was reached from IFD0, such as IFD0/Exif/Interop or IFD0/SubIFD1.
+	Path     string
+	Offset   int64
+	IFD      EXIFIFD
+	Children []*IFDNode
+	//Err is set when the IFD could not be read or decrypted, the node then has no entries.
+	Err error
+}
+
+//Tree holds the chain of top level IFDs of a document, every IFD reachable from them is one of their descendants.
+type Tree struct {
+	IFDs []*IFDNode
+}
+
+//childIFDs maps tags which point to other IFDs to the name of those IFDs in a path.
+var childIFDs = map[IFDtag]string{
+	SubIFDs:             "SubIFD",
+	ExifTag:             "Exif",
+	GPSTag:              "GPS",
+	InteroperabilityTag: "Interop",
+	DNGPrivateData:      "DNGPrivateData",
+	IDC_IFD:             "IDC",
+	IDC2_IFD:            "IDC2",
+	MakerNote:           "MakerNote",
+}
+
+//ReadTree reads every IFD of a little or big endian TIFF document such as ARW.
+func ReadTree(r io.ReadSeeker) (*Tree, error) {
+	f, err := NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return f.ReadTree()
+}
+
+//ReadTree reads the IFD0 chain and follows every entry pointing to another IFD, each IFD is read only once.
+//An IFD below the chain which can not be read is kept as a node with Err set and the rest of the tree is still read.
+//DNGPrivateData is only followed to the SR2SubIFD in files made by Sony, other makers store their own data there.
+//On error in the chain itself the part of the tree read so far is returned.
+func (f *File) ReadTree() (*Tree, error) {
+	tr := treeReader{f: f, seen: make(map[int64]bool)}
+	if ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0); err == nil {
+		tr.sony = sonyMake(ifd0)
+	}
+	var t Tree
+	for offset, i := int64(f.Header.Offset), 0; offset != 0; i++ {
+		if tr.seen[offset] {
+			return &t, errors.New("IFD chain loops back to offset: " + fmt.Sprint(offset))
+		}
+		if i == maxIFDChain {
+			return &t, errors.New("IFD chain longer than " + fmt.Sprint(maxIFDChain))
+		}
+
+		node, err := tr.read("IFD"+fmt.Sprint(i), offset)
+		if node != nil {
+			t.IFDs = append(t.IFDs, node)
+		}
+		if err != nil {
+			return &t, err
+		}
+		offset = int64(node.IFD.Offset)
+	}
+	return &t, nil
+}
+
+type treeReader struct {
+	f    *File
+	seen map[int64]bool
+	sony bool //Make of IFD0 is SONY
+}
+
+//read parses the IFD at offset and its descendants, failures below it are recorded on the nodes.
+func (tr *treeReader) read(path string, offset int64) (*IFDNode, error) {
+	tr.seen[offset] = true
+	ifd, err := tr.f.ExtractMetaData(offset, 0)
+	if err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	node := &IFDNode{Path: path, Offset: offset, IFD: ifd}
+
+	for i, fia := range ifd.FIA {
+		name, ok := childIFDs[fia.Tag]
+		if !ok || (fia.Tag == DNGPrivateData && !tr.sony) {
+			continue
+		}
+
+		if fia.Tag == MakerNote {
+			//Makernotes are not required to be IFDs, those which can not be parsed are left out
+			if makernote, offset, _, err := readMakerNote(tr.f, fia); err == nil && !tr.seen[offset] {
+				tr.seen[offset] = true
+				node.Children = append(node.Children, &IFDNode{Path: path + "/" + name, Offset: offset, IFD: makernote})
+			}
+			continue
+		}
+
+		offsets := []uint32{fia.Offset}
+		if long := ifd.FIAvals[i].long; long != nil {
+			offsets = *long
+		}
+		for n, offset := range offsets {
+			childPath := path + "/" + name
+			if fia.Tag == SubIFDs {
+				childPath += fmt.Sprint(n)
+			}
+			if offset == 0 || tr.seen[int64(offset)] {
+				continue
+			}
+
+			child, err := tr.read(childPath, int64(offset))
+			if err != nil {
+				node.Children = append(node.Children, &IFDNode{Path: childPath, Offset: int64(offset), Err: err})
+				continue
+			}
+			node.Children = append(node.Children, child)
+
+			if fia.Tag == DNGPrivateData {
+				tr.readSR2(child)
+			}
+		}
+	}
+
+	return node, nil
+}
+
+//readSR2 adds the decrypted SR2SubIFD referenced by a DNGPrivateData IFD as its child.
+func (tr *treeReader) readSR2(dng *IFDNode) {
+	offset, length, key := sr2Location(dng.IFD)
+	if offset == 0 || length == 0 || tr.seen[int64(offset)] {
+		return
+	}
+	tr.seen[int64(offset)] = true
+
+	sr2, err := decryptSR2IFD(tr.f, offset, length, key)
+	if err != nil {
+		err = errors.New(dng.Path + "/SR2: " + err.Error())
+	}
+	dng.Children = append(dng.Children, &IFDNode{Path: dng.Path + "/SR2", Offset: int64(offset), IFD: sr2, Err: err})
+}
+
+//Walk calls fn for every IFD in the tree, parents before their children, stopping at the first error.
+func (t *Tree) Walk(fn func(n *IFDNode) error) error {
+	var walk func(nodes []*IFDNode) error
+	walk = func(nodes []*IFDNode) error {
+		for _, n := range nodes {
+			if err := fn(n); err != nil {
+				return err
+			}
+			if err := walk(n.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(t.IFDs)
+}
+
+//Find returns the IFD with the given path, or nil if the document does not have it.
+func (t *Tree) Find(path string) *IFDNode {
+	var find func(nodes []*IFDNode) *IFDNode
+	find = func(nodes []*IFDNode) *IFDNode {
+		for _, n := range nodes {
+			if n.Path == path {
+				return n
+			}
+			if strings.HasPrefix(path, n.Path+"/") {
+				return find(n.Children)
+			}
+		}
+		return nil
+	}
+	return find(t.IFDs)
+}
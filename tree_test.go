@@ -0,0 +1,107 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadTree(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		tree, err := ReadTree(bytes.NewReader(buildTreeTIFF(order, 0)))
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+
+		var paths []string
+		tree.Walk(func(n *IFDNode) error {
+			paths = append(paths, n.Path)
+			return nil
+		})
+		expected := []string{"IFD0", "IFD0/SubIFD0", "IFD0/SubIFD1", "IFD0/Exif", "IFD0/Exif/Interop", "IFD1"}
+		if len(paths) != len(expected) {
+			t.Errorf("%v: expected %v, got %v", order, expected, paths)
+			continue
+		}
+		for i := range expected {
+			if paths[i] != expected[i] {
+				t.Errorf("%v: expected %v, got %v", order, expected, paths)
+				break
+			}
+		}
+
+		interop := tree.Find("IFD0/Exif/Interop")
+		if interop == nil || interop.Offset != 600 || string(*interop.IFD.FIAvals[0].ascii) != "R98\x00" {
+			t.Errorf("%v: unexpected interop IFD %+v", order, interop)
+		}
+		if sub := tree.Find("IFD0/SubIFD1"); sub == nil || (*sub.IFD.FIAvals[0].short)[0] != 1616 {
+			t.Errorf("%v: unexpected SubIFD1 %+v", order, sub)
+		}
+		if tree.Find("IFD0/GPS") != nil {
+			t.Errorf("%v: expected the GPS pointer back to IFD0 to be skipped", order)
+		}
+	}
+}
+
+func TestReadTreeLoop(t *testing.T) {
+	tree, err := ReadTree(bytes.NewReader(buildTreeTIFF(binary.LittleEndian, 8)))
+	if err == nil {
+		t.Error("Expected the IFD chain looping back to IFD0 to fail")
+	}
+	if tree == nil || len(tree.IFDs) != 2 {
+		t.Error("Expected the IFDs before the loop to be returned")
+	}
+}
+
+func TestReadTreeDamaged(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		//A SubIFD outside of the file leaves the Exif IFD readable
+		doc := buildTIFF(order,
+			testBlock{entries: []testEntry{{SubIFDs, LONG, []uint32{50000}}, {ExifTag, LONG, []uint32{300}}}},
+			testBlock{offset: 300, entries: []testEntry{{FNumber, RATIONAL, []uint32{28, 10}}}},
+		)
+		tree, err := ReadTree(bytes.NewReader(doc))
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+		if sub := tree.Find("IFD0/SubIFD0"); sub == nil || sub.Err == nil || sub.Offset != 50000 {
+			t.Errorf("%v: expected the failure to be recorded on the SubIFD, got %+v", order, sub)
+		}
+		if exif := tree.Find("IFD0/Exif"); exif == nil || exif.Err != nil || len(exif.IFD.FIA) != 1 {
+			t.Errorf("%v: expected the Exif IFD to be read, got %+v", order, exif)
+		}
+
+		//An SR2SubIFD which can not be decrypted is recorded the same way
+		doc = buildSR2TIFF(order, []testEntry{{ImageWidth, SHORT, []uint16{6000}}}, []testEntry{{BlackLevel2, SHORT, []uint16{512, 512, 512, 512}}})
+		tree, err = ReadTree(bytes.NewReader(doc))
+		if sr2 := tree.Find("IFD0/DNGPrivateData/SR2"); err != nil || sr2 == nil || sr2.Err != nil || len(sr2.IFD.FIA) != 1 {
+			t.Errorf("%v: expected the SR2SubIFD to be read, got %+v %v", order, sr2, err)
+		}
+		tree, err = ReadTree(bytes.NewReader(doc[:604]))
+		if err != nil {
+			t.Error(order, err)
+			continue
+		}
+		if sr2 := tree.Find("IFD0/DNGPrivateData/SR2"); sr2 == nil || sr2.Err == nil {
+			t.Errorf("%v: expected the failure to be recorded on the SR2SubIFD, got %+v", order, sr2)
+		}
+		if tree.Find("IFD0/SubIFD0") == nil {
+			t.Errorf("%v: expected the raw IFD to be read", order)
+		}
+	}
+}
+
+func TestReadTreeForeignDNGPrivateData(t *testing.T) {
+	//DNG writers store a blob starting with their name, it is not an IFD
+	doc := buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{Make, ASCII, []byte("Canon\x00")}, {DNGPrivateData, BYTE, []byte("Adobe\x00MakN\x00\x00\x00\x08")}}})
+	tree, err := ReadTree(bytes.NewReader(doc))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if tree.Find("IFD0/DNGPrivateData") != nil {
+		t.Error("Expected DNGPrivateData of a DNG not to be read as an IFD")
+	}
+}
@@ -0,0 +1,147 @@
+package arw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+//Rational is a RATIONAL or SRATIONAL value as stored in the file.
+type Rational struct {
+	Num int64
+	Den int64
+}
+
+//Float returns the quotient of the rational, a zero denominator results in an infinity or NaN.
+func (r Rational) Float() float64 {
+	return float64(r.Num) / float64(r.Den)
+}
+
+func (r Rational) String() string {
+	return fmt.Sprint(r.Num, "/", r.Den)
+}
+
+//TypeError is returned by the accessors of Value when an entry holds values of another type.
+type TypeError struct {
+	Tag  IFDtag
+	Type IFDtype
+	Want string //Kind of value the accessor returns
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%v holds %v values, not %v", e.Tag, e.Type, e.Want)
+}
+
+//Value is the typed content of an IFD entry.
+type Value struct {
+	Tag   IFDtag
+	Type  IFDtype
+	Count uint32
+	val   FIAval
+}
+
+//Lookup returns the value of the first entry with the given tag.
+func (e EXIFIFD) Lookup(tag IFDtag) (Value, bool) {
+	for i, fia := range e.FIA {
+		if fia.Tag == tag && i < len(e.FIAvals) {
+			return Value{Tag: tag, Type: fia.Type, Count: fia.Count, val: e.FIAvals[i]}, true
+		}
+	}
+	return Value{}, false
+}
+
+//Uint returns the first value of a BYTE, SHORT or LONG entry.
+func (v Value) Uint() (uint64, error) {
+	var u uint64
+	switch {
+	case v.Type == BYTE && v.val.ascii != nil && len(*v.val.ascii) > 0:
+		u = uint64((*v.val.ascii)[0])
+	case v.Type == SHORT && v.val.short != nil && len(*v.val.short) > 0:
+		u = uint64((*v.val.short)[0])
+	case v.Type == LONG && v.val.long != nil && len(*v.val.long) > 0:
+		u = uint64((*v.val.long)[0])
+	case v.Type == BYTE || v.Type == SHORT || v.Type == LONG:
+		return 0, errors.New(fmt.Sprint(v.Tag) + " has no values")
+	default:
+		return 0, &TypeError{v.Tag, v.Type, "unsigned integer"}
+	}
+	return u, nil
+}
+
+//Ints returns the values of a BYTE, SHORT, SSHORT, LONG or SLONG entry.
+func (v Value) Ints() ([]int64, error) {
+	var ints []int64
+	switch {
+	case v.Type == BYTE && v.val.ascii != nil:
+		for _, b := range *v.val.ascii {
+			ints = append(ints, int64(b))
+		}
+	case v.Type == SHORT && v.val.short != nil:
+		for _, s := range *v.val.short {
+			ints = append(ints, int64(s))
+		}
+	case v.Type == SSHORT && v.val.sshort != nil:
+		for _, s := range *v.val.sshort {
+			ints = append(ints, int64(s))
+		}
+	case v.Type == LONG && v.val.long != nil:
+		for _, l := range *v.val.long {
+			ints = append(ints, int64(l))
+		}
+	case v.Type == SLONG && v.val.slong != nil:
+		for _, l := range *v.val.slong {
+			ints = append(ints, int64(l))
+		}
+	default:
+		return nil, &TypeError{v.Tag, v.Type, "integer"}
+	}
+	return ints, nil
+}
+
+//Rational returns the first value of a RATIONAL or SRATIONAL entry.
+func (v Value) Rational() (Rational, error) {
+	rats, err := v.Rationals()
+	if err != nil {
+		return Rational{}, err
+	}
+	if len(rats) == 0 {
+		return Rational{}, errors.New(fmt.Sprint(v.Tag) + " has no values")
+	}
+	return rats[0], nil
+}
+
+//Rationals returns the values of a RATIONAL or SRATIONAL entry.
+func (v Value) Rationals() ([]Rational, error) {
+	if (v.Type != RATIONAL && v.Type != SRATIONAL) || v.val.frac == nil {
+		return nil, &TypeError{v.Tag, v.Type, "rational"}
+	}
+	return *v.val.frac, nil
+}
+
+//Text returns an ASCII entry up to its first NUL.
+func (v Value) Text() (string, error) {
+	if v.Type != ASCII || v.val.ascii == nil {
+		return "", &TypeError{v.Tag, v.Type, "ASCII"}
+	}
+	text := *v.val.ascii
+	if i := bytes.IndexByte(text, 0); i >= 0 {
+		text = text[:i]
+	}
+	return string(text), nil
+}
+
+//String returns the text of ASCII entries and the values of all others as formatted by FIAval.
+func (v Value) String() string {
+	if text, err := v.Text(); err == nil {
+		return text
+	}
+	return v.val.String()
+}
+
+//Bytes returns the bytes of a BYTE, ASCII or UNDEFINED entry, sharing them with the IFD.
+func (v Value) Bytes() ([]byte, error) {
+	if (v.Type != BYTE && v.Type != ASCII && v.Type != UNDEFINED) || v.val.ascii == nil {
+		return nil, &TypeError{v.Tag, v.Type, "bytes"}
+	}
+	return *v.val.ascii, nil
+}
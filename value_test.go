@@ -0,0 +1,86 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		doc := buildTIFF(order, testBlock{entries: []testEntry{
+			{ImageWidth, SHORT, []uint16{6000}},
+			{Make, ASCII, []byte("SONY\x00")},
+			{Orientation, BYTE, []byte{6}},
+			{StripOffsets, LONG, []uint32{123456, 7}},
+			{WB_RGGBLevels, SSHORT, []int16{2600, 1024, 1024, -5}},
+			{ExposureTime, RATIONAL, []uint32{10, 2500}},
+			{ExposureBiasValue, SRATIONAL, []int32{-2, 3}},
+		}})
+		f, err := NewFile(bytes.NewReader(doc))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		ifd, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		lookup := func(tag IFDtag) Value {
+			v, ok := ifd.Lookup(tag)
+			if !ok {
+				t.Fatal(order, tag, "missing")
+			}
+			return v
+		}
+
+		if u, err := lookup(ImageWidth).Uint(); err != nil || u != 6000 {
+			t.Error(order, "ImageWidth:", u, err)
+		}
+		if u, err := lookup(Orientation).Uint(); err != nil || u != 6 {
+			t.Error(order, "Orientation:", u, err)
+		}
+		if ints, err := lookup(StripOffsets).Ints(); err != nil || len(ints) != 2 || ints[0] != 123456 || ints[1] != 7 {
+			t.Error(order, "StripOffsets:", ints, err)
+		}
+		if ints, err := lookup(WB_RGGBLevels).Ints(); err != nil || len(ints) != 4 || ints[3] != -5 {
+			t.Error(order, "WB_RGGBLevels:", ints, err)
+		}
+		if r, err := lookup(ExposureTime).Rational(); err != nil || r != (Rational{10, 2500}) || r.Float() != 0.004 {
+			t.Error(order, "ExposureTime:", r, err)
+		}
+		if r, err := lookup(ExposureBiasValue).Rational(); err != nil || r != (Rational{-2, 3}) {
+			t.Error(order, "ExposureBiasValue:", r, err)
+		}
+		if s, err := lookup(Make).Text(); err != nil || s != "SONY" {
+			t.Errorf("%v Make: %q %v", order, s, err)
+		}
+		if b, err := lookup(Make).Bytes(); err != nil || string(b) != "SONY\x00" {
+			t.Errorf("%v Make: %q %v", order, b, err)
+		}
+		if s := lookup(ExposureTime).String(); s != "0.004" {
+			t.Errorf("%v ExposureTime: %q", order, s)
+		}
+
+		if _, ok := ifd.Lookup(Model); ok {
+			t.Error(order, "Lookup of a missing tag succeeded")
+		}
+		mismatches := []error{}
+		_, err = lookup(WB_RGGBLevels).Uint()
+		mismatches = append(mismatches, err)
+		_, err = lookup(ExposureTime).Ints()
+		mismatches = append(mismatches, err)
+		_, err = lookup(ImageWidth).Rational()
+		mismatches = append(mismatches, err)
+		_, err = lookup(ImageWidth).Text()
+		mismatches = append(mismatches, err)
+		_, err = lookup(StripOffsets).Bytes()
+		mismatches = append(mismatches, err)
+		for i, err := range mismatches {
+			if _, ok := err.(*TypeError); !ok {
+				t.Errorf("%v mismatch %v: expected a TypeError, got %v", order, i, err)
+			}
+		}
+	}
+}
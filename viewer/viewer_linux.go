@@ -1,3 +1,8 @@
+//The viewer was split off the decoder and still uses its internals, it is kept out of the build until it is ported
+//to the exported API. The Linux viewer needs GTK 3, the Windows one draws with GDI.
+
+//go:build ignore
+
 package viewer
 
 import (
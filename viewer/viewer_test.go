@@ -1,3 +1,5 @@
+//go:build ignore
+
 package viewer
 
 import (
@@ -26,11 +28,14 @@ func TestViewer(t *testing.T) {
 
 	switch rw.rawType {
 	case raw14:
-		rendered16bit = readRaw14(buf, rw)
+		rendered16bit, err = readRaw14(buf, rw, nil)
 	case craw:
-		rendered16bit = readCRAW(buf, rw)
+		rendered16bit, err = readCRAW(buf, rw, nil)
 	default:
-		t.Error("Unhanded RAW type:", rw.rawType)
+		t.Fatal("Unhanded RAW type:", rw.rawType)
+	}
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	asRGBA := image.NewRGBA(rendered16bit.Rect)
@@ -0,0 +1,142 @@
+package arw
+
+import (
+	"image"
+)
+
+//VNG implements Variable Number of Gradients interpolation as described by Chang, Cheung and Pang.
+//For every pixel the gradients in eight directions are measured in a 5x5 neighbourhood and only the
+//smoothest directions contribute to the colour differences which are added to the pixel's own value.
+type VNG struct{}
+
+type vngPair struct {
+	ax, ay, bx, by int
+	weight         int32 //1 for full, 2 for half weight
+}
+
+type vngDirection struct {
+	pairs  []vngPair
+	region [][2]int
+}
+
+var vngDirections = buildVNGDirections()
+
+//buildVNGDirections rotates the north and north east templates in to all eight directions.
+func buildVNGDirections() [8]vngDirection {
+	north := vngDirection{
+		pairs: []vngPair{
+			{0, -1, 0, 1, 1}, {0, -2, 0, 0, 1},
+			{-1, -1, -1, 1, 2}, {1, -1, 1, 1, 2},
+			{-1, -2, -1, 0, 2}, {1, -2, 1, 0, 2},
+		},
+		region: [][2]int{{0, -1}, {0, -2}, {-1, -1}, {1, -1}, {-1, -2}, {1, -2}},
+	}
+	northEast := vngDirection{
+		pairs: []vngPair{
+			{1, -1, -1, 1, 1}, {2, -2, 0, 0, 1},
+			{1, -2, -1, 0, 2}, {2, -1, 0, 1, 2},
+			{0, -1, -2, 1, 2}, {1, 0, -1, 2, 2},
+		},
+		region: [][2]int{{1, -1}, {2, -2}, {1, -2}, {2, -1}},
+	}
+
+	rotate := func(d vngDirection) vngDirection {
+		var r vngDirection
+		for _, p := range d.pairs {
+			r.pairs = append(r.pairs, vngPair{-p.ay, p.ax, -p.by, p.bx, p.weight})
+		}
+		for _, p := range d.region {
+			r.region = append(r.region, [2]int{-p[1], p[0]})
+		}
+		return r
+	}
+
+	var dirs [8]vngDirection
+	dirs[0], dirs[1] = north, northEast
+	for i := 2; i < 8; i++ {
+		dirs[i] = rotate(dirs[i-2])
+	}
+	return dirs
+}
+
+func (v VNG) Demosaic(cfa *CFA) *RGB14 {
+	img := NewRGB14(cfa.Rect)
+	v.demosaicRows(cfa, img, cfa.Rect.Min.Y, cfa.Rect.Max.Y)
+	return img
+}
+
+func (VNG) demosaicRows(cfa *CFA, img *RGB14, y0, y1 int) {
+	const border = 2
+	borderInterpolate(cfa, img, border, y0, y1)
+
+	r := cfa.Rect
+	inner := r.Inset(border).Intersect(image.Rect(r.Min.X, y0, r.Max.X, y1))
+	for y := inner.Min.Y; y < inner.Max.Y; y++ {
+		for x := inner.Min.X; x < inner.Max.X; x++ {
+			img.set(x-r.Min.X, y-r.Min.Y, vngAt(cfa, x, y))
+		}
+	}
+}
+
+func vngAt(cfa *CFA, x, y int) pixel16 {
+	var gradients [8]int32
+	min, max := int32(-1), int32(0)
+	for d := range vngDirections {
+		var g int32
+		for _, p := range vngDirections[d].pairs {
+			diff := int32(cfa.at(x+p.ax, y+p.ay)) - int32(cfa.at(x+p.bx, y+p.by))
+			if diff < 0 {
+				diff = -diff
+			}
+			g += diff * 2 / p.weight
+		}
+		gradients[d] = g
+		if min < 0 || g < min {
+			min = g
+		}
+		if g > max {
+			max = g
+		}
+	}
+
+	//Threshold from the paper, k1 = 1.5 and k2 = 0.5
+	threshold := min*3/2 + (max-min)/2
+	own := cfa.Color(x, y)
+	center := int32(cfa.at(x, y))
+
+	var diffSum, selected [3]int32
+	for d := range vngDirections {
+		if gradients[d] > threshold {
+			continue
+		}
+
+		var sum, count [3]int32
+		sum[own], count[own] = center, 1
+		for _, p := range vngDirections[d].region {
+			c := cfa.Color(x+p[0], y+p[1])
+			sum[c] += int32(cfa.at(x+p[0], y+p[1]))
+			count[c]++
+		}
+
+		ownAvg := sum[own] / count[own]
+		for c := range sum {
+			if count[c] > 0 {
+				diffSum[c] += sum[c]/count[c] - ownAvg
+				selected[c]++
+			}
+		}
+	}
+
+	var p pixel16
+	for c := uint8(0); c < 3; c++ {
+		switch {
+		case c == own:
+		case selected[c] == 0:
+			setChannel(&p, c, channel(bilinearAt(cfa, x, y), c))
+		default:
+			setChannel(&p, c, clamp14(center+diffSum[c]/selected[c]))
+		}
+	}
+	setChannel(&p, own, uint16(center))
+	return p
+}
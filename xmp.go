@@ -0,0 +1,246 @@
+package arw
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//Namespaces of the XMP properties which are decoded.
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNS = "http://ns.adobe.com/xap/1.0/"
+	dcNS  = "http://purl.org/dc/elements/1.1/"
+	crsNS = "http://ns.adobe.com/camera-raw-settings/1.0/"
+)
+
+//XMPMeta holds the properties of an XMP packet this package understands.
+type XMPMeta struct {
+	HasRating bool
+	Rating    int //-1 for rejected, 0 to 5 stars
+	Label     string
+	Keywords  []string
+
+	//Develop holds the simple Camera Raw settings such as Exposure2012 or WhiteBalance by property name.
+	Develop map[string]string
+}
+
+//XMP returns the XMP packet embedded in IFD0.
+func (f *File) XMP() ([]byte, error) {
+	ifd0, err := f.ExtractMetaData(int64(f.Header.Offset), 0)
+	if err != nil {
+		return nil, err
+	}
+	for i, fia := range ifd0.FIA {
+		if fia.Tag == XMP && ifd0.FIAvals[i].ascii != nil {
+			return *ifd0.FIAvals[i].ascii, nil
+		}
+	}
+	return nil, errors.New("no XMP packet")
+}
+
+//ParseXMP decodes an XMP packet, properties may be given as attributes of rdf:Description or as elements.
+func ParseXMP(packet []byte) (*XMPMeta, error) {
+	x := &XMPMeta{Develop: make(map[string]string)}
+	//Packets are often padded with NULs to leave room for edits
+	d := xml.NewDecoder(bytes.NewReader(bytes.TrimRight(packet, "\x00")))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return x, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Space != rdfNS || start.Name.Local != "Description" {
+			continue
+		}
+
+		for _, attr := range start.Attr {
+			x.set(attr.Name, attr.Value, nil)
+		}
+		if err := x.readProperties(d); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//readProperties reads the property elements of an rdf:Description up to its end.
+func (x *XMPMeta) readProperties(d *xml.Decoder) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			value, items, err := readProperty(d)
+			if err != nil {
+				return err
+			}
+			x.set(tok.Name, value, items)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+//readProperty returns the text of a simple property or the rdf:li items of an array, nested structures are skipped.
+func readProperty(d *xml.Decoder) (string, []string, error) {
+	var text strings.Builder
+	var items []string
+	for depth := 0; ; {
+		tok, err := d.Token()
+		if err != nil {
+			return "", nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Space == rdfNS && tok.Name.Local == "li" {
+				var item string
+				if err := d.DecodeElement(&item, &tok); err != nil {
+					return "", nil, err
+				}
+				items = append(items, strings.TrimSpace(item))
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return strings.TrimSpace(text.String()), items, nil
+			}
+			depth--
+		case xml.CharData:
+			if depth == 0 {
+				text.Write(tok)
+			}
+		}
+	}
+}
+
+func (x *XMPMeta) set(name xml.Name, value string, items []string) {
+	switch {
+	case name.Space == xmpNS && name.Local == "Rating":
+		if rating, err := strconv.ParseFloat(value, 64); err == nil {
+			x.HasRating = true
+			x.Rating = int(rating)
+		}
+	case name.Space == xmpNS && name.Local == "Label":
+		x.Label = value
+	case name.Space == dcNS && name.Local == "subject":
+		x.Keywords = appendKeywords(x.Keywords, items...)
+	case name.Space == crsNS && items == nil && value != "":
+		x.Develop[name.Local] = value
+	}
+}
+
+//appendKeywords appends the keywords which are not yet in the list.
+func appendKeywords(keywords []string, add ...string) []string {
+	for _, k := range add {
+		found := false
+		for _, existing := range keywords {
+			if existing == k {
+				found = true
+				break
+			}
+		}
+		if !found && k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+//Merge applies the properties of a sidecar on top of x, keywords of both are kept.
+func (x *XMPMeta) Merge(sidecar *XMPMeta) {
+	if sidecar.HasRating {
+		x.HasRating = true
+		x.Rating = sidecar.Rating
+	}
+	if sidecar.Label != "" {
+		x.Label = sidecar.Label
+	}
+	x.Keywords = appendKeywords(x.Keywords, sidecar.Keywords...)
+	if x.Develop == nil {
+		x.Develop = make(map[string]string)
+	}
+	for k, v := range sidecar.Develop {
+		x.Develop[k] = v
+	}
+}
+
+//SidecarPath returns the path of the XMP sidecar of a raw file, either with its extension replaced or appended to it.
+//An empty path is returned when there is no sidecar.
+func SidecarPath(path string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, candidate := range []string{base + ".xmp", base + ".XMP", path + ".xmp", path + ".XMP"} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+//ReadXMP returns the XMP packet embedded in the ARW at path merged with its sidecar, if any.
+//Either source may be missing or damaged, an error is returned only when neither can be read.
+func ReadXMP(path string) (*XMPMeta, error) {
+	embedded, embeddedErr := readEmbeddedXMP(path)
+
+	sidecar := SidecarPath(path)
+	if sidecar == "" {
+		if embeddedErr != nil {
+			return nil, errors.New(path + ": " + embeddedErr.Error() + ", no sidecar")
+		}
+		return embedded, nil
+	}
+	side, sideErr := readSidecarXMP(sidecar)
+	switch {
+	case embeddedErr != nil && sideErr != nil:
+		return nil, errors.New(path + ": " + embeddedErr.Error() + ", " + sideErr.Error())
+	case sideErr != nil:
+		return embedded, nil
+	case embeddedErr != nil:
+		return side, nil
+	}
+	embedded.Merge(side)
+	return embedded, nil
+}
+
+//readEmbeddedXMP decodes the XMP packet in IFD0 of the file at path.
+func readEmbeddedXMP(path string) (*XMPMeta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := NewFile(file)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := f.XMP()
+	if err != nil {
+		return nil, err
+	}
+	return ParseXMP(packet)
+}
+
+//readSidecarXMP decodes the sidecar file at path.
+func readSidecarXMP(path string) (*XMPMeta, error) {
+	packet, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	x, err := ParseXMP(packet)
+	if err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	return x, nil
+}
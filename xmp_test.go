@@ -0,0 +1,131 @@
+package arw
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testXMP = `<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:crs="http://ns.adobe.com/camera-raw-settings/1.0/"
+   xmp:Rating="3"
+   crs:WhiteBalance="As Shot"
+   crs:Exposure2012="+0.35">
+   <xmp:Label>Red</xmp:Label>
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>harbour</rdf:li>
+     <rdf:li>night</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+   <crs:Contrast2012>+12</crs:Contrast2012>
+   <crs:ToneCurvePV2012>
+    <rdf:Seq>
+     <rdf:li>0, 0</rdf:li>
+     <rdf:li>255, 255</rdf:li>
+    </rdf:Seq>
+   </crs:ToneCurvePV2012>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+const testSidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:crs="http://ns.adobe.com/camera-raw-settings/1.0/" xmp:Rating="-1" crs:Exposure2012="-0.50">
+   <dc:subject><rdf:Bag><rdf:li>night</rdf:li><rdf:li>ferry</rdf:li></rdf:Bag></dc:subject>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func TestParseXMP(t *testing.T) {
+	x, err := ParseXMP(append([]byte(testXMP), 0, 0, 0))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !x.HasRating || x.Rating != 3 || x.Label != "Red" {
+		t.Error("Unexpected rating or label:", x.HasRating, x.Rating, x.Label)
+	}
+	if !reflect.DeepEqual(x.Keywords, []string{"harbour", "night"}) {
+		t.Error("Unexpected keywords:", x.Keywords)
+	}
+	expected := map[string]string{"WhiteBalance": "As Shot", "Exposure2012": "+0.35", "Contrast2012": "+12"}
+	if !reflect.DeepEqual(x.Develop, expected) {
+		t.Error("Unexpected develop settings:", x.Develop)
+	}
+}
+
+func TestReadXMPSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "DSC00001.ARW")
+	doc := buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{XMP, UNDEFINED, []byte(testXMP)}}})
+	if err := ioutil.WriteFile(path, doc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "DSC00001.xmp"), []byte(testSidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := ReadXMP(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !x.HasRating || x.Rating != -1 || x.Label != "Red" {
+		t.Error("Unexpected rating or label:", x.HasRating, x.Rating, x.Label)
+	}
+	if !reflect.DeepEqual(x.Keywords, []string{"harbour", "night", "ferry"}) {
+		t.Error("Unexpected keywords:", x.Keywords)
+	}
+	if x.Develop["Exposure2012"] != "-0.50" || x.Develop["Contrast2012"] != "+12" {
+		t.Error("Unexpected develop settings:", x.Develop)
+	}
+
+	//Without embedded packet and sidecar there is nothing to read
+	bare := filepath.Join(dir, "DSC00002.ARW")
+	if err := ioutil.WriteFile(bare, buildTIFF(binary.LittleEndian, testBlock{entries: []testEntry{{ImageWidth, SHORT, []uint16{6000}}}}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadXMP(bare); err == nil {
+		t.Error("Expected an error without XMP")
+	}
+
+	//A damaged raw file leaves the sidecar readable
+	damaged := filepath.Join(dir, "DSC00003.ARW")
+	if err := ioutil.WriteFile(damaged, []byte("II*\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "DSC00003.xmp"), []byte(testSidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if x, err := ReadXMP(damaged); err != nil || x.Rating != -1 || x.Label != "" {
+		t.Error("Expected the sidecar to be read:", x, err)
+	}
+
+	//A damaged sidecar leaves the embedded packet readable
+	if err := ioutil.WriteFile(path, doc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "DSC00001.xmp"), []byte("<x:xmpmeta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if x, err := ReadXMP(path); err != nil || x.Develop["Exposure2012"] != "+0.35" {
+		t.Error("Expected the embedded packet to be read:", x, err)
+	}
+
+	//Both damaged fails
+	if err := ioutil.WriteFile(filepath.Join(dir, "DSC00003.xmp"), []byte("<x:xmpmeta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadXMP(damaged); err == nil {
+		t.Error("Expected an error with both sources damaged")
+	}
+}